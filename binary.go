@@ -0,0 +1,197 @@
+package fwd
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrOverflow is returned by [Reader.ReadUvarint]
+// and [Reader.ReadVarint] when a varint does not
+// fit in a 64-bit integer.
+var ErrOverflow = errors.New("fwd: varint overflows a 64-bit integer")
+
+// maxVarString is the longest string that
+// ReadVarString accepts when neither a token
+// size nor a buffer size limit has been set
+const maxVarString = 64 << 20
+
+// ReadUint16 reads a 2-byte unsigned
+// integer encoded with 'bo'. Like [Reader.Next],
+// it returns [io.ErrUnexpectedEOF] if the stream
+// ends before the integer has been read.
+func (r *Reader) ReadUint16(bo binary.ByteOrder) (uint16, error) {
+	b, err := r.Next(2)
+	if err != nil {
+		return 0, err
+	}
+	return bo.Uint16(b), nil
+}
+
+// ReadUint32 reads a 4-byte unsigned
+// integer encoded with 'bo'. Like [Reader.Next],
+// it returns [io.ErrUnexpectedEOF] if the stream
+// ends before the integer has been read.
+func (r *Reader) ReadUint32(bo binary.ByteOrder) (uint32, error) {
+	b, err := r.Next(4)
+	if err != nil {
+		return 0, err
+	}
+	return bo.Uint32(b), nil
+}
+
+// ReadUint64 reads an 8-byte unsigned
+// integer encoded with 'bo'. Like [Reader.Next],
+// it returns [io.ErrUnexpectedEOF] if the stream
+// ends before the integer has been read.
+func (r *Reader) ReadUint64(bo binary.ByteOrder) (uint64, error) {
+	b, err := r.Next(8)
+	if err != nil {
+		return 0, err
+	}
+	return bo.Uint64(b), nil
+}
+
+// ReadInt8 reads a 1-byte signed integer.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream has ended.
+func (r *Reader) ReadInt8() (int8, error) {
+	b, err := r.Next(1)
+	if err != nil {
+		return 0, err
+	}
+	return int8(b[0]), nil
+}
+
+// ReadInt16 reads a 2-byte two's complement
+// integer encoded with 'bo', returning errors
+// under the same conditions as [Reader.ReadUint16].
+func (r *Reader) ReadInt16(bo binary.ByteOrder) (int16, error) {
+	u, err := r.ReadUint16(bo)
+	return int16(u), err
+}
+
+// ReadInt32 reads a 4-byte two's complement
+// integer encoded with 'bo', returning errors
+// under the same conditions as [Reader.ReadUint32].
+func (r *Reader) ReadInt32(bo binary.ByteOrder) (int32, error) {
+	u, err := r.ReadUint32(bo)
+	return int32(u), err
+}
+
+// ReadInt64 reads an 8-byte two's complement
+// integer encoded with 'bo', returning errors
+// under the same conditions as [Reader.ReadUint64].
+func (r *Reader) ReadInt64(bo binary.ByteOrder) (int64, error) {
+	u, err := r.ReadUint64(bo)
+	return int64(u), err
+}
+
+// ReadFloat32 reads a 4-byte IEEE-754
+// floating-point number encoded with 'bo',
+// returning errors under the same conditions
+// as [Reader.ReadUint32].
+func (r *Reader) ReadFloat32(bo binary.ByteOrder) (float32, error) {
+	u, err := r.ReadUint32(bo)
+	return math.Float32frombits(u), err
+}
+
+// ReadFloat64 reads an 8-byte IEEE-754
+// floating-point number encoded with 'bo',
+// returning errors under the same conditions
+// as [Reader.ReadUint64].
+func (r *Reader) ReadFloat64(bo binary.ByteOrder) (float64, error) {
+	u, err := r.ReadUint64(bo)
+	return math.Float64frombits(u), err
+}
+
+// ReadUvarint reads an unsigned varint
+// (as encoded by [binary.PutUvarint]). It returns
+// [io.EOF] if no bytes were read, [io.ErrUnexpectedEOF]
+// if the stream ends in the middle of the varint,
+// and [ErrOverflow] if the varint is more than
+// [binary.MaxVarintLen64] bytes long. The reader
+// is not advanced if an error is returned.
+func (r *Reader) ReadUvarint() (uint64, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+
+	// fill until we have the final
+	// byte of the varint or as many
+	// bytes as a varint could need
+	for r.buffered() < binary.MaxVarintLen64 && r.state == nil && !hasVarintEnd(r.data[r.n:]) {
+		r.more()
+	}
+	if r.buffered() < 1 {
+		return 0, r.err()
+	}
+	x, n := binary.Uvarint(r.data[r.n:])
+	if n == 0 {
+		return 0, r.noEOF()
+	}
+	if n < 0 {
+		return 0, ErrOverflow
+	}
+	r.n += n
+	r.observe()
+	return x, nil
+}
+
+// ReadVarint reads a signed varint
+// (as encoded by [binary.PutVarint]), returning
+// errors under the same conditions as [Reader.ReadUvarint].
+func (r *Reader) ReadVarint() (int64, error) {
+	ux, err := r.ReadUvarint()
+	x := int64(ux >> 1)
+	if ux&1 != 0 {
+		x = ^x
+	}
+	return x, err
+}
+
+// ReadVarString reads a string preceded by its
+// length as an unsigned varint. The length is
+// checked against the limits set with
+// [Reader.SetMaxTokenSize] and [Reader.SetMaxBufferSize]
+// before any of the string is read, returning
+// [ErrTokenTooLong] or [ErrBufferLimitExceeded]
+// respectively, so that a corrupt or malicious
+// prefix cannot cause a huge allocation. If
+// neither limit is set, lengths over 64 MiB are
+// rejected with [ErrTokenTooLong]; set a larger
+// token size to read longer strings. In any case
+// the string grows as its bytes arrive rather than
+// being allocated up front. The length is consumed
+// even if it is rejected.
+// ReadVarString returns [io.EOF] if the stream
+// ends before the length and [io.ErrUnexpectedEOF]
+// along with the bytes that were read if it ends
+// in the middle of the string.
+func (r *Reader) ReadVarString() (string, error) {
+	l, err := r.ReadUvarint()
+	if err != nil {
+		return "", err
+	}
+	if r.maxToken > 0 && l > uint64(r.maxToken) {
+		return "", ErrTokenTooLong
+	}
+	if r.maxBuf > 0 && l > uint64(r.maxBuf) {
+		return "", ErrBufferLimitExceeded
+	}
+	if r.maxToken == 0 && r.maxBuf == 0 && l > maxVarString {
+		return "", ErrTokenTooLong
+	}
+	// each limit is an int, so 'l' fits in one
+	return r.ReadStringN(int(l))
+}
+
+// hasVarintEnd returns whether 'b'
+// contains a byte without the continuation bit
+func hasVarintEnd(b []byte) bool {
+	for _, c := range b {
+		if c < 0x80 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package fwd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestReadUint(t *testing.T) {
+	for _, bo := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		var buf []byte
+		scratch := make([]byte, 8)
+		for i := 0; i < 50; i++ {
+			bo.PutUint16(scratch, uint16(i*1031))
+			buf = append(buf, scratch[:2]...)
+			bo.PutUint32(scratch, uint32(i*1000003))
+			buf = append(buf, scratch[:4]...)
+			bo.PutUint64(scratch, uint64(i)*0x9e3779b97f4a7c15)
+			buf = append(buf, scratch...)
+		}
+		buf = append(buf, 1, 2, 3)
+
+		// the tiny buffer forces the
+		// integers to straddle refills
+		rd := NewReaderSize(partialReader{bytes.NewReader(buf)}, 16)
+		for i := 0; i < 50; i++ {
+			u16, err := rd.ReadUint16(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u16 != uint16(i*1031) {
+				t.Fatalf("expected %d; got %d", uint16(i*1031), u16)
+			}
+			u32, err := rd.ReadUint32(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u32 != uint32(i*1000003) {
+				t.Fatalf("expected %d; got %d", uint32(i*1000003), u32)
+			}
+			u64, err := rd.ReadUint64(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u64 != uint64(i)*0x9e3779b97f4a7c15 {
+				t.Fatalf("expected %d; got %d", uint64(i)*0x9e3779b97f4a7c15, u64)
+			}
+		}
+		if _, err := rd.ReadUint32(bo); err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
+func TestReadInt(t *testing.T) {
+	for _, bo := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		var buf []byte
+		scratch := make([]byte, 8)
+		for i := -25; i < 25; i++ {
+			buf = append(buf, byte(int8(i*5)))
+			bo.PutUint16(scratch, uint16(int16(i*1031)))
+			buf = append(buf, scratch[:2]...)
+			bo.PutUint32(scratch, uint32(int32(i*1000003)))
+			buf = append(buf, scratch[:4]...)
+			bo.PutUint64(scratch, uint64(int64(i)*0x1e3779b97f4a7c15))
+			buf = append(buf, scratch...)
+		}
+		buf = append(buf, 1, 2, 3)
+
+		rd := NewReaderSize(partialReader{bytes.NewReader(buf)}, 16)
+		for i := -25; i < 25; i++ {
+			i8, err := rd.ReadInt8()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i8 != int8(i*5) {
+				t.Fatalf("expected %d; got %d", int8(i*5), i8)
+			}
+			i16, err := rd.ReadInt16(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i16 != int16(i*1031) {
+				t.Fatalf("expected %d; got %d", int16(i*1031), i16)
+			}
+			i32, err := rd.ReadInt32(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i32 != int32(i*1000003) {
+				t.Fatalf("expected %d; got %d", int32(i*1000003), i32)
+			}
+			i64, err := rd.ReadInt64(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i64 != int64(i)*0x1e3779b97f4a7c15 {
+				t.Fatalf("expected %d; got %d", int64(i)*0x1e3779b97f4a7c15, i64)
+			}
+		}
+		if _, err := rd.ReadInt64(bo); err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
+func TestReadFloat(t *testing.T) {
+	f32s := []float32{0, 1.5, -3.25, math.MaxFloat32, float32(math.Inf(-1))}
+	f64s := []float64{0, math.Pi, -1e300, math.SmallestNonzeroFloat64, math.Inf(1)}
+	for _, bo := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		var buf []byte
+		scratch := make([]byte, 8)
+		for i := range f32s {
+			bo.PutUint32(scratch, math.Float32bits(f32s[i]))
+			buf = append(buf, scratch[:4]...)
+			bo.PutUint64(scratch, math.Float64bits(f64s[i]))
+			buf = append(buf, scratch...)
+		}
+		buf = append(buf, 1, 2, 3)
+
+		rd := NewReaderSize(partialReader{bytes.NewReader(buf)}, 16)
+		for i := range f32s {
+			f32, err := rd.ReadFloat32(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f32 != f32s[i] {
+				t.Fatalf("expected %g; got %g", f32s[i], f32)
+			}
+			f64, err := rd.ReadFloat64(bo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f64 != f64s[i] {
+				t.Fatalf("expected %g; got %g", f64s[i], f64)
+			}
+		}
+		if _, err := rd.ReadFloat32(bo); err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
+func TestReadVarint(t *testing.T) {
+	vals := []int64{0, 1, -1, 63, -64, 64, 1 << 20, -(1 << 40), 1<<63 - 1, -1 << 63}
+	var buf []byte
+	scratch := make([]byte, binary.MaxVarintLen64)
+	for _, v := range vals {
+		buf = append(buf, scratch[:binary.PutVarint(scratch, v)]...)
+		buf = append(buf, scratch[:binary.PutUvarint(scratch, uint64(v))]...)
+	}
+
+	rd := NewReaderSize(partialReader{bytes.NewReader(buf)}, 16)
+	for _, v := range vals {
+		x, err := rd.ReadVarint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x != v {
+			t.Fatalf("expected %d; got %d", v, x)
+		}
+		ux, err := rd.ReadUvarint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ux != uint64(v) {
+			t.Fatalf("expected %d; got %d", uint64(v), ux)
+		}
+	}
+	if _, err := rd.ReadUvarint(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+
+	// truncated
+	rd = NewReaderSize(bytes.NewReader([]byte{0x80, 0x80}), 16)
+	if _, err := rd.ReadUvarint(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// too long
+	rd = NewReaderSize(bytes.NewReader(bytes.Repeat([]byte{0xff}, 11)), 16)
+	if _, err := rd.ReadUvarint(); err != ErrOverflow {
+		t.Fatalf("expected %q; got %v", ErrOverflow, err)
+	}
+}
+
+func TestReadVarString(t *testing.T) {
+	strs := []string{"", "hello", string(bytes.Repeat([]byte{'x'}, 300))}
+	var buf []byte
+	scratch := make([]byte, binary.MaxVarintLen64)
+	for _, s := range strs {
+		buf = append(buf, scratch[:binary.PutUvarint(scratch, uint64(len(s)))]...)
+		buf = append(buf, s...)
+	}
+
+	rd := NewReaderSize(partialReader{bytes.NewReader(buf)}, 16)
+	for _, want := range strs {
+		s, err := rd.ReadVarString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != want {
+			t.Fatalf("expected %q; got %q", want, s)
+		}
+	}
+	if _, err := rd.ReadVarString(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+
+	// truncated
+	rd = NewReaderSize(bytes.NewReader([]byte("\x05abc")), 16)
+	if s, err := rd.ReadVarString(); err != io.ErrUnexpectedEOF || s != "abc" {
+		t.Fatalf("expected (%q, %q); got (%q, %v)", "abc", io.ErrUnexpectedEOF, s, err)
+	}
+
+	// a huge length is rejected before reading
+	huge := scratch[:binary.PutUvarint(scratch, 1<<40)]
+	rd = NewReaderSize(bytes.NewReader(huge), 16)
+	rd.SetMaxTokenSize(100)
+	if _, err := rd.ReadVarString(); err != ErrTokenTooLong {
+		t.Fatalf("expected %q; got %v", ErrTokenTooLong, err)
+	}
+	rd = NewReaderSize(bytes.NewReader(huge), 16)
+	rd.SetMaxBufferSize(1024)
+	if _, err := rd.ReadVarString(); err != ErrBufferLimitExceeded {
+		t.Fatalf("expected %q; got %v", ErrBufferLimitExceeded, err)
+	}
+
+	// and without any limit set
+	rd = NewReaderSize(bytes.NewReader(huge), 16)
+	if _, err := rd.ReadVarString(); err != ErrTokenTooLong {
+		t.Fatalf("expected %q; got %v", ErrTokenTooLong, err)
+	}
+
+	// a length under the default cap is
+	// not allocated before it is read
+	short := append(scratch[:binary.PutUvarint(scratch, maxVarString)], "abc"...)
+	rd = NewReaderSize(bytes.NewReader(short), 16)
+	if s, err := rd.ReadVarString(); err != io.ErrUnexpectedEOF || s != "abc" {
+		t.Fatalf("expected (%q, %q); got (%q, %v)", "abc", io.ErrUnexpectedEOF, s, err)
+	}
+}
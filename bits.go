@@ -0,0 +1,85 @@
+package fwd
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrBitCount is returned by [BitReader.ReadBits]
+// when asked for more than 64 bits or a negative
+// number of bits.
+var ErrBitCount = errors.New("fwd: bit count out of range")
+
+// BitReader reads a stream of bits, most
+// significant bit first, from a [Reader].
+// Whole bytes are read from the Reader as they
+// are needed; the bits of the current byte that
+// have not been read yet are held by the BitReader.
+// To switch back to byte-oriented reads on the
+// Reader, call [BitReader.Align] first.
+type BitReader struct {
+	r     *Reader
+	cur   byte // the byte being read
+	nbits uint // unread bits in cur (the low bits)
+}
+
+// NewBitReader returns a new *BitReader
+// that reads from 'r'.
+func NewBitReader(r *Reader) *BitReader {
+	return &BitReader{r: r}
+}
+
+// ReadBits reads the next 'n' bits and returns them
+// in the low bits of the result, with the first bit
+// read as the most significant. 'n' must be between 0
+// and 64, or ReadBits returns [ErrBitCount]. If the
+// stream ends before any bits are read, ReadBits
+// returns [io.EOF]; if it ends part of the way through,
+// it returns [io.ErrUnexpectedEOF], and the bits that
+// were read are lost.
+func (b *BitReader) ReadBits(n int) (uint64, error) {
+	if n < 0 || n > 64 {
+		return 0, ErrBitCount
+	}
+	var v uint64
+	want := uint(n)
+	for want > 0 {
+		if b.nbits == 0 {
+			c, err := b.r.ReadByte()
+			if err != nil {
+				if err == io.EOF && want < uint(n) {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			b.cur, b.nbits = c, 8
+		}
+		take := want
+		if take > b.nbits {
+			take = b.nbits
+		}
+		bits := uint64(b.cur>>(b.nbits-take)) & (1<<take - 1)
+		v = v<<take | bits
+		b.nbits -= take
+		want -= take
+	}
+	return v, nil
+}
+
+// ReadBit reads a single bit.
+func (b *BitReader) ReadBit() (bool, error) {
+	v, err := b.ReadBits(1)
+	return v == 1, err
+}
+
+// Align discards the unread bits of the current
+// byte, so that the next bit read is the first bit
+// of the next byte, and so that the underlying
+// [Reader] is positioned at the same place in the
+// stream as the BitReader. It returns the number
+// of bits discarded.
+func (b *BitReader) Align() int {
+	n := int(b.nbits)
+	b.nbits = 0
+	return n
+}
@@ -0,0 +1,56 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReadBits(t *testing.T) {
+	// 101 | 0110 0 | 1111 1111 ...
+	in := []byte{0xac, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x80, 'x', 0xf0}
+	br := NewBitReader(NewReaderSize(bytes.NewReader(in), 16))
+
+	v, err := br.ReadBits(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 5 {
+		t.Fatalf("expected %d; got %d", 5, v)
+	}
+	if v, _ = br.ReadBits(4); v != 6 {
+		t.Fatalf("expected %d; got %d", 6, v)
+	}
+	if bit, _ := br.ReadBit(); bit {
+		t.Fatal("expected a zero bit")
+	}
+	// straddles nine bytes
+	if v, _ = br.ReadBits(64); v != 0x7fffffffffffffff {
+		t.Fatalf("expected %#x; got %#x", uint64(0x7fffffffffffffff), v)
+	}
+	if bit, _ := br.ReadBit(); !bit {
+		t.Fatal("expected a one bit")
+	}
+
+	// back to bytes
+	if n := br.Align(); n != 7 {
+		t.Fatalf("expected to discard %d bits; discarded %d", 7, n)
+	}
+	c, err := br.r.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != 'x' {
+		t.Fatalf("expected %q; got %q", 'x', c)
+	}
+
+	if _, err := br.ReadBits(65); err != ErrBitCount {
+		t.Fatalf("expected ErrBitCount; got %v", err)
+	}
+	if _, err := br.ReadBits(12); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if _, err := br.ReadBits(1); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+}
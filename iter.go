@@ -0,0 +1,71 @@
+//go:build go1.23
+// +build go1.23
+
+package fwd
+
+import (
+	"bufio"
+	"io"
+	"iter"
+)
+
+// Blocks returns an iterator over successive
+// 'size'-byte blocks of the stream. The reader is
+// advanced past each block as it is yielded, and the
+// final block may be shorter than 'size' if the stream
+// ends in the middle of it. Each yielded slice aliases
+// the buffer and is only valid until the next step of
+// the iteration. Iteration stops at the end of the
+// stream; any other error is yielded once, with a nil
+// block, and ends the iteration. If 'size' is less than
+// 1, the iterator yields [bufio.ErrNegativeCount].
+func (r *Reader) Blocks(size int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if size < 1 {
+			yield(nil, bufio.ErrNegativeCount)
+			return
+		}
+		for {
+			b, err := r.Next(size)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				if len(b) > 0 {
+					// short block at the end
+					r.discard(len(b))
+					yield(b, nil)
+				}
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Lines returns an iterator over the lines of
+// the stream, as read by [Reader.ReadLine]: the
+// trailing "\n" or "\r\n" is stripped, and a final
+// line without a newline is yielded like any other.
+// Each yielded line is newly allocated and may be
+// retained. Iteration stops at the end of the stream;
+// any other error is yielded once, along with the
+// partial line read before it, and ends the iteration.
+// Breaking out of the loop leaves the reader positioned
+// after the last line yielded.
+func (r *Reader) Lines() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			line, err := r.ReadLine()
+			if err == io.EOF {
+				return
+			}
+			if !yield(line, err) || err != nil {
+				return
+			}
+		}
+	}
+}
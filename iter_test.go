@@ -0,0 +1,97 @@
+//go:build go1.23
+// +build go1.23
+
+package fwd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+
+	var out []byte
+	var blocks int
+	for b, err := range rd.Blocks(64) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks++
+		if blocks < 16 && len(b) != 64 {
+			t.Fatalf("block %d: expected %d bytes; got %d", blocks, 64, len(b))
+		}
+		out = append(out, b...)
+	}
+	if blocks != 16 {
+		t.Fatalf("expected %d blocks; got %d", 16, blocks)
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("bytes not equal")
+	}
+
+	// stopping early leaves the rest in the reader
+	rd = NewReaderSize(bytes.NewReader(bts), 16)
+	for range rd.Blocks(100) {
+		break
+	}
+	if rd.Offset() != 100 {
+		t.Fatalf("expected Offset() to be %d; got %d", 100, rd.Offset())
+	}
+
+	// errors are yielded once
+	bad := errors.New("bad")
+	rd = NewReader(&errReader{r: bytes.NewReader(bts[:20]), err: bad})
+	var errs, good int
+	for _, err := range rd.Blocks(8) {
+		if err == nil {
+			good++
+			continue
+		}
+		if err != bad {
+			t.Fatalf("expected %v; got %v", bad, err)
+		}
+		errs++
+	}
+	if good != 2 || errs != 1 {
+		t.Fatalf("expected %d blocks and %d error; got %d and %d", 2, 1, good, errs)
+	}
+}
+
+func TestLines(t *testing.T) {
+	in := "one\r\ntwo\n\n" + strings.Repeat("x", 50) + "\nlast"
+	want := []string{"one", "two", "", strings.Repeat("x", 50), "last"}
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+
+	var got []string
+	for line, err := range rd.Lines() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(line))
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d lines; got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line %d: expected %q; got %q", i, want[i], got[i])
+		}
+	}
+
+	// break out and resume with other methods
+	rd = NewReaderSize(strings.NewReader(in), 16)
+	for range rd.Lines() {
+		break
+	}
+	b, err := rd.Next(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "two" {
+		t.Fatalf("expected %q; got %q", "two", b)
+	}
+}
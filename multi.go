@@ -0,0 +1,114 @@
+package fwd
+
+import (
+	"errors"
+	"io"
+)
+
+// MultiReader returns a reader that is the
+// logical concatenation of 'readers', like
+// [io.MultiReader]. If every reader is an
+// [io.Seeker], the returned reader is also an
+// [io.Seeker] that translates offsets across the
+// boundaries between readers, so that a [Reader]
+// reading from it can still skip by seeking.
+// Each reader is considered to begin at its
+// position when MultiReader is called.
+func MultiReader(readers ...io.Reader) io.Reader {
+	m := &multiSeeker{
+		parts: make([]io.ReadSeeker, len(readers)),
+		start: make([]int64, len(readers)),
+		size:  make([]int64, len(readers)),
+	}
+	for i, r := range readers {
+		rs, ok := r.(io.ReadSeeker)
+		if !ok {
+			return io.MultiReader(readers...)
+		}
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return io.MultiReader(readers...)
+		}
+		end, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return io.MultiReader(readers...)
+		}
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return io.MultiReader(readers...)
+		}
+		m.parts[i] = rs
+		m.start[i] = start
+		m.size[i] = max64(end-start, 0)
+	}
+	return m
+}
+
+// multiSeeker is the seekable
+// counterpart to io.MultiReader
+type multiSeeker struct {
+	parts []io.ReadSeeker
+	start []int64 // position of each part when added
+	size  []int64 // size of each part after start
+	cur   int     // index of the part being read
+	off   int64   // logical offset
+}
+
+func (m *multiSeeker) Read(p []byte) (int, error) {
+	for m.cur < len(m.parts) {
+		n, err := m.parts[m.cur].Read(p)
+		m.off += int64(n)
+		if err != io.EOF {
+			return n, err
+		}
+		// move on to the next part,
+		// which may have been seeked away
+		// from its start in the meantime
+		m.cur++
+		if m.cur < len(m.parts) {
+			if _, err := m.parts[m.cur].Seek(m.start[m.cur], io.SeekStart); err != nil {
+				return n, err
+			}
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, io.EOF
+}
+
+func (m *multiSeeker) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = m.off + offset
+	case io.SeekEnd:
+		target = offset
+		for _, s := range m.size {
+			target += s
+		}
+	default:
+		return m.off, errors.New("fwd: invalid whence")
+	}
+	if target < 0 {
+		return m.off, errors.New("fwd: negative position")
+	}
+
+	// find the part containing 'target'
+	i, pos := 0, target
+	for i < len(m.parts) && pos >= m.size[i] {
+		pos -= m.size[i]
+		i++
+	}
+	if i < len(m.parts) {
+		if _, err := m.parts[i].Seek(m.start[i]+pos, io.SeekStart); err != nil {
+			return m.off, err
+		}
+	}
+	// otherwise we're at (or past) the end,
+	// and subsequent reads return io.EOF
+	m.cur = i
+	m.off = target
+	return target, nil
+}
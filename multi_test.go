@@ -0,0 +1,84 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMultiReader(t *testing.T) {
+	bts := randomBts(3000)
+	parts := func() []io.Reader {
+		return []io.Reader{
+			bytes.NewReader(bts[:1000]),
+			bytes.NewReader(nil),
+			bytes.NewReader(bts[1000:1500]),
+			bytes.NewReader(bts[1500:]),
+		}
+	}
+
+	mr := MultiReader(parts()...)
+	if _, ok := mr.(io.Seeker); !ok {
+		t.Fatal("expected a seekable reader")
+	}
+	all, err := ioutil.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, bts) {
+		t.Fatal("bytes not equal")
+	}
+
+	// skips should seek across parts
+	c := readCounter{r: MultiReader(parts()...)}
+	rd := NewReaderSize(struct {
+		io.Reader
+		io.Seeker
+	}{&c, c.r.(io.Seeker)}, 64)
+	for _, pos := range []int{10, 999, 1000, 1001, 1499, 2999} {
+		rd.Skip(pos - int(rd.Offset()))
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[pos] {
+			t.Fatalf("at %d: expected %d; got %d", pos, bts[pos], b)
+		}
+	}
+	if c.count > 12 {
+		t.Fatalf("expected Skip() to seek; made %d reads", c.count)
+	}
+
+	// seeking backwards and relative to the end
+	ms := MultiReader(parts()...).(io.ReadSeeker)
+	for _, off := range []int64{-1, -2000, -3000} {
+		pos, err := ms.Seek(off, io.SeekEnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos != 3000+off {
+			t.Fatalf("expected position %d; got %d", 3000+off, pos)
+		}
+		rest, err := ioutil.ReadAll(ms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rest, bts[pos:]) {
+			t.Fatalf("from %d: bytes not equal", pos)
+		}
+	}
+
+	// any unseekable part disables seeking
+	mr = MultiReader(bytes.NewReader(bts[:10]), partialReader{bytes.NewReader(bts[10:])})
+	if _, ok := mr.(io.Seeker); ok {
+		t.Fatal("expected an unseekable reader")
+	}
+	all, err = ioutil.ReadAll(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, bts) {
+		t.Fatal("bytes not equal")
+	}
+}
@@ -0,0 +1,66 @@
+package fwd
+
+import "io"
+
+// Option configures a [Reader] created
+// with [NewReaderOptions].
+type Option func(r *Reader)
+
+// NewReaderOptions returns a new *Reader that
+// reads from 'r' and is configured by 'opts',
+// which are applied in order. Without options,
+// it is equivalent to [NewReader].
+func NewReaderOptions(r io.Reader, opts ...Option) *Reader {
+	rd := NewReader(r)
+	for _, opt := range opts {
+		opt(rd)
+	}
+	return rd
+}
+
+// WithBufferSize sets the size of the read
+// buffer, like [NewReaderSize].
+func WithBufferSize(n int) Option {
+	return func(r *Reader) {
+		r.data = make([]byte, 0, max(n, minReaderSize))
+	}
+}
+
+// WithEOFPolicy sets whether short reads
+// return [io.ErrUnexpectedEOF] or [io.EOF],
+// like [Reader.SetPromoteEOF].
+func WithEOFPolicy(promote bool) Option {
+	return func(r *Reader) { r.SetPromoteEOF(promote) }
+}
+
+// WithLimit limits the number of bytes read
+// from the underlying reader, like [Reader.Limit].
+func WithLimit(n int64) Option {
+	return func(r *Reader) { r.Limit(n) }
+}
+
+// WithMaxEmptyReads sets the number of empty
+// reads tolerated from the underlying reader,
+// like [Reader.SetMaxEmptyReads].
+func WithMaxEmptyReads(n int) Option {
+	return func(r *Reader) { r.SetMaxEmptyReads(n) }
+}
+
+// WithMaxTokenSize limits the length of strings
+// read by [Reader.ReadCString], like
+// [Reader.SetMaxTokenSize].
+func WithMaxTokenSize(n int) Option {
+	return func(r *Reader) { r.SetMaxTokenSize(n) }
+}
+
+// WithTee copies consumed bytes to 'w',
+// like [Reader.Tee].
+func WithTee(w io.Writer) Option {
+	return func(r *Reader) { r.Tee(w) }
+}
+
+// WithPositionTracking enables line and column
+// tracking, like [Reader.EnablePositionTracking].
+func WithPositionTracking() Option {
+	return func(r *Reader) { r.EnablePositionTracking() }
+}
@@ -0,0 +1,47 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNewReaderOptions(t *testing.T) {
+	bts := randomBts(1000)
+	var tee bytes.Buffer
+	rd := NewReaderOptions(bytes.NewReader(bts),
+		WithBufferSize(100),
+		WithEOFPolicy(false),
+		WithLimit(500),
+		WithMaxEmptyReads(3),
+		WithTee(&tee),
+		WithPositionTracking(),
+	)
+	if rd.BufferSize() != 100 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 100, rd.BufferSize())
+	}
+	if rd.maxEmptyReads() != 3 {
+		t.Fatalf("expected %d max empty reads; got %d", 3, rd.maxEmptyReads())
+	}
+	if !rd.track {
+		t.Fatal("expected position tracking to be enabled")
+	}
+
+	out := make([]byte, 600)
+	n, err := rd.ReadFull(out)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if n != 500 {
+		t.Fatalf("expected to read %d bytes; read %d", 500, n)
+	}
+	if !bytes.Equal(tee.Bytes(), bts[:500]) {
+		t.Fatal("teed bytes not equal")
+	}
+
+	// no options
+	rd = NewReaderOptions(bytes.NewReader(bts))
+	if rd.BufferSize() != DefaultReaderSize {
+		t.Fatalf("expected BufferSize() to be %d; got %d", DefaultReaderSize, rd.BufferSize())
+	}
+}
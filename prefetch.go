@@ -0,0 +1,142 @@
+package fwd
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned by a [PrefetchReader]
+// that is read from after it has been closed.
+var ErrClosed = errors.New("fwd: reader closed")
+
+// PrefetchReader is a [Reader] whose underlying
+// reader is read ahead of time by a background
+// goroutine, so that slow reads (for example, from
+// a remote file system) overlap with the processing
+// of data that has already arrived. Every [Reader]
+// method is available and behaves as it would on a
+// plain Reader, and errors from the underlying reader
+// are returned in the order they were encountered.
+//
+// The background goroutine runs until the underlying
+// reader returns an error or [PrefetchReader.Close]
+// is called. Because the goroutine reads from the
+// underlying reader, replacing it with [Reader.Reset]
+// does not stop the prefetching; call Close first.
+type PrefetchReader struct {
+	*Reader
+	p *prefetcher
+}
+
+// NewPrefetchReader returns a new *PrefetchReader
+// that reads from 'r' in 'n'-byte chunks and keeps
+// up to 'bufs' chunks read ahead of the consumer.
+// 'bufs' is raised to 2 if it is smaller, and the
+// read buffer of the returned reader is 'n' bytes.
+func NewPrefetchReader(r io.Reader, n, bufs int) *PrefetchReader {
+	n = max(n, minReaderSize)
+	bufs = max(bufs, 2)
+	p := &prefetcher{
+		free:  make(chan []byte, bufs),
+		ready: make(chan chunk, bufs),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < bufs; i++ {
+		p.free <- make([]byte, n)
+	}
+	go p.run(r)
+	return &PrefetchReader{
+		Reader: NewReaderSize(p, n),
+		p:      p,
+	}
+}
+
+// Close stops the background goroutine. A read
+// on the underlying reader that is already in
+// progress is not interrupted, but its result is
+// discarded. Subsequent reads of bytes that were
+// not already buffered return [ErrClosed].
+// Close always returns nil.
+func (p *PrefetchReader) Close() error {
+	p.p.once.Do(func() { close(p.p.done) })
+	return nil
+}
+
+// chunk is the result of one read
+// by the background goroutine
+type chunk struct {
+	b   []byte
+	err error
+}
+
+// prefetcher is an io.Reader that hands
+// out chunks read by a background goroutine
+type prefetcher struct {
+	free  chan []byte // empty chunks for the goroutine
+	ready chan chunk  // chunks waiting to be read
+	done  chan struct{}
+	once  sync.Once
+
+	cur []byte // the chunk being read
+	off int    // read offset into cur
+	err error  // error following cur
+}
+
+func (p *prefetcher) run(r io.Reader) {
+	for {
+		var b []byte
+		select {
+		case b = <-p.free:
+		case <-p.done:
+			return
+		}
+		n, err := r.Read(b[:cap(b)])
+		select {
+		case p.ready <- chunk{b: b[:n], err: err}:
+		case <-p.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *prefetcher) Read(b []byte) (int, error) {
+	for p.off == len(p.cur) {
+		if p.cur != nil {
+			// hand the chunk back; this never
+			// blocks, because free has room for
+			// every chunk
+			p.free <- p.cur
+			p.cur, p.off = nil, 0
+		}
+		if p.err != nil {
+			return 0, p.err
+		}
+		select {
+		case <-p.done:
+			return 0, ErrClosed
+		default:
+		}
+		select {
+		case c := <-p.ready:
+			p.cur, p.err = c.b, c.err
+			if len(c.b) == 0 {
+				// don't recycle the
+				// chunk twice
+				p.free <- c.b
+				p.cur = nil
+				if p.err == nil {
+					return 0, nil
+				}
+			}
+		case <-p.done:
+			return 0, ErrClosed
+		}
+	}
+	n := copy(b, p.cur[p.off:])
+	p.off += n
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package fwd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestPrefetchReader(t *testing.T) {
+	bts := randomBts(10000)
+	rd := NewPrefetchReader(partialReader{bytes.NewReader(bts)}, 64, 4)
+	defer rd.Close()
+
+	head, err := rd.Peek(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(head, bts[:100]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if _, err := rd.Skip(1000); err != nil {
+		t.Fatal(err)
+	}
+	out, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[1000:]) {
+		t.Fatal("bytes not equal")
+	}
+
+	// errors arrive after the bytes that preceded them
+	bad := errors.New("bad")
+	rd = NewPrefetchReader(&errReader{r: bytes.NewReader(bts[:500]), err: bad}, 16, 2)
+	out = make([]byte, 1000)
+	n, err := rd.ReadFull(out)
+	if err != bad {
+		t.Fatalf("expected %v; got %v", bad, err)
+	}
+	if n != 500 || !bytes.Equal(out[:n], bts[:500]) {
+		t.Fatalf("expected the first %d bytes; got %d", 500, n)
+	}
+	rd.Close()
+
+	// reading after Close
+	rd = NewPrefetchReader(bytes.NewReader(bts), 16, 2)
+	rd.Close()
+	rd.Close()
+	if _, err := rd.Next(100); err != ErrClosed {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+	if _, err := ioutil.ReadAll(rd); err != ErrClosed {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+}
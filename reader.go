@@ -34,16 +34,86 @@
 package fwd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"hash"
 	"io"
-	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 const (
 	// DefaultReaderSize is the default size of the read buffer
 	DefaultReaderSize = 2048
 
+	// DefaultMaxEmptyReads is the default number of
+	// consecutive empty reads tolerated before a
+	// read fails with io.ErrNoProgress
+	DefaultMaxEmptyReads = 100
+
 	// minimum read buffer; straight from bufio
 	minReaderSize = 16
+
+	// largest allocation made for bytes that
+	// have been asked for but not yet read
+	readChunk = 32 << 10
+)
+
+var (
+	// ErrInvalidMark is returned by [Reader.Rewind]
+	// when the mark is not outstanding.
+	ErrInvalidMark = errors.New("fwd: invalid mark")
+
+	// ErrNoRewind is returned by [Reader.Skip]
+	// when the reader cannot move backwards
+	// by the requested number of bytes.
+	ErrNoRewind = errors.New("fwd: cannot rewind")
+
+	// ErrNotSeeker is returned by [Reader.Seek]
+	// when the underlying reader is not an [io.Seeker].
+	ErrNotSeeker = errors.New("fwd: underlying reader is not an io.Seeker")
+
+	// ErrNotReaderAt is returned by [Reader.ReadAt]
+	// when the underlying reader is not an [io.ReaderAt].
+	ErrNotReaderAt = errors.New("fwd: underlying reader is not an io.ReaderAt")
+
+	// ErrNoDeadline is returned by [Reader.PeekDeadline]
+	// when the underlying reader does not have a
+	// SetReadDeadline method.
+	ErrNoDeadline = errors.New("fwd: underlying reader does not support deadlines")
+
+	// ErrNotCloser is returned by [Reader.Close]
+	// when the underlying reader is not an [io.Closer].
+	ErrNotCloser = errors.New("fwd: underlying reader is not an io.Closer")
+
+	// ErrNeedMore may be returned by the callback
+	// passed to [Reader.ScanBytes] to ask for more
+	// bytes than are currently buffered. The callback
+	// may also return an error that wraps ErrNeedMore;
+	// it is matched with [errors.Is].
+	ErrNeedMore = errors.New("fwd: need more data")
+
+	// ErrTokenTooLong is returned by [Reader.ReadCString]
+	// and [Reader.ReadVarString] when the string
+	// is longer than the limit set
+	// with [Reader.SetMaxTokenSize] (or, for
+	// ReadVarString, than its default limit).
+	ErrTokenTooLong = errors.New("fwd: token too long")
+
+	// ErrBufferFull is returned by [Reader.ReadSlice]
+	// when the delimiter does not occur within
+	// the buffer, and by [Reader.Peek] when the
+	// buffer is too small and growing it has been
+	// disabled with [Reader.SetPeekGrowsBuffer].
+	ErrBufferFull = errors.New("fwd: buffer full")
+
+	// ErrBufferLimitExceeded is returned when the
+	// buffer would have to grow beyond the limit set
+	// with [Reader.SetMaxBufferSize].
+	ErrBufferLimitExceeded = errors.New("fwd: buffer limit exceeded")
 )
 
 // NewReader returns a new *Reader that reads from 'r'
@@ -68,12 +138,13 @@ func NewReaderBuf(r io.Reader, buf []byte) *Reader {
 	}
 	buf = buf[:0]
 	rd := &Reader{
-		r:    r,
-		data: buf,
-	}
-	if s, ok := r.(io.Seeker); ok {
-		rd.rs = s
+		data:         buf,
+		lastByte:     -1,
+		lastRuneSize: -1,
+		limit:        -1,
+		limitSet:     -1,
 	}
+	rd.setSource(r)
 	return rd
 }
 
@@ -86,51 +157,496 @@ type Reader struct {
 	n     int    // read offset
 	state error  // last read error
 
+	// the last byte returned by a read,
+	// or -1 if it may not be unread
+	lastByte int
+
+	// the size of the last rune returned
+	// by ReadRune, or -1 if it may not be unread
+	lastRuneSize int
+
+	base  int64   // stream position of data[0]
+	marks []int64 // outstanding marks
+
+	// the number of bytes that may still be
+	// read from r, or -1 if there is no limit
+	limit    int64
+	limitSet int64 // the last argument to Limit
+
+	settings
+
+	tee   io.Writer // consumed bytes are copied here
+	hash  hash.Hash // consumed bytes are hashed here
+	lines int       // newlines consumed
+	col   int       // bytes consumed since the last newline
+	seen  int64     // stream position of the next byte to observe
+
+	// observing is set when any of tee, hash,
+	// or track is, so that the fast paths
+	// only have to check one field
+	observing bool
+
+	reads  int   // calls to r.Read
+	filled int64 // bytes returned by r.Read
+
+	token    []byte // the last token returned by Scan
+	scanErr  error  // the error that stopped Scan
+	scanDone bool   // Scan has returned false
+
 	// if the reader past to NewReader was
 	// also an io.Seeker, this is non-nil
 	rs io.Seeker
+
+	// likewise for io.ReaderAt
+	ra io.ReaderAt
+
+	// and for read deadlines (like net.Conn)
+	dl readDeadliner
+
+	// and for io.Closer
+	rc io.Closer
+
+	// the size of rs, or -1 if it
+	// hasn't been determined yet
+	size int64
+}
+
+// settings holds the options that describe how
+// a Reader behaves, as opposed to the state of the
+// stream it is reading. The zero value is the
+// default for each one. [Reader.Reset] restores
+// the defaults, and [Reader.ResetKeepOptions]
+// keeps them.
+type settings struct {
+	// if set, short reads return io.EOF
+	// instead of io.ErrUnexpectedEOF
+	plainEOF bool
+
+	maxEmpty int             // see SetMaxEmptyReads; 0 means the default
+	emptyErr error           // see SetEmptyReadError; nil means the default
+	maxToken int             // see SetMaxTokenSize; 0 means no limit
+	maxWrite int             // see SetWriteChunkSize; 0 means no limit
+	maxBuf   int             // see SetMaxBufferSize; 0 means no limit
+	seekMin  int             // see SetSeekThreshold; 0 means the default
+	ahead    int             // see SetReadAhead; 0 means no limit
+	noGrow   bool            // see SetPeekGrowsBuffer
+	track    bool            // see EnablePositionTracking
+	split    bufio.SplitFunc // see SetSplit; nil means bufio.ScanLines
+}
+
+// BufferedReader is the set of methods shared
+// by [*Reader] and [*bufio.Reader], so that code
+// accepting a BufferedReader works with either.
+type BufferedReader interface {
+	io.Reader
+	io.ByteScanner
+	io.RuneScanner
+	io.WriterTo
+	Peek(n int) ([]byte, error)
+	Discard(n int) (int, error)
+	Buffered() int
+	Size() int
+	ReadSlice(delim byte) ([]byte, error)
+	ReadBytes(delim byte) ([]byte, error)
+	ReadString(delim byte) (string, error)
 }
 
+var (
+	_ BufferedReader = (*Reader)(nil)
+	_ BufferedReader = (*bufio.Reader)(nil)
+	_ io.ReadSeeker  = (*Reader)(nil)
+	_ io.ReaderAt    = (*Reader)(nil)
+	_ io.ReadCloser  = (*Reader)(nil)
+)
+
 // Reset resets the underlying reader
 // and the read buffer.
+//
+// Reset clears everything: the state of the stream
+// being read (buffered bytes, errors, marks, the
+// offset and position, and the read counters), the
+// limit set by [Reader.Limit], the writers set by
+// [Reader.Tee] and [Reader.Hash], and every option
+// set with the reader's Set methods and
+// [Reader.EnablePositionTracking], which return to
+// their defaults. Only the buffer itself is reused.
+// Use [Reader.ResetKeepOptions] to keep the options.
 func (r *Reader) Reset(rd io.Reader) {
-	r.r = rd
+	r.ResetSize(rd, cap(r.data))
+}
+
+// ResetKeepOptions is like [Reader.Reset], but it
+// only clears the state of the stream: the options
+// (the EOF policy, position tracking, whether Peek
+// grows the buffer, the empty-read settings, the split
+// function, and the token, write chunk, buffer,
+// read-ahead, and seek threshold sizes) are kept,
+// although the position starts over at line 1.
+// The tee and hash writers are kept too, and the
+// limit most recently passed to [Reader.Limit] is
+// re-applied to the new underlying reader. This suits
+// a reader that is reused across connections.
+func (r *Reader) ResetKeepOptions(rd io.Reader) {
+	opts, tee, h, limit := r.settings, r.tee, r.hash, r.limitSet
+	r.Reset(rd)
+	r.settings = opts
+	r.tee, r.hash = tee, h
+	r.setObserving()
+	r.limit, r.limitSet = limit, limit
+}
+
+// ResetWith is like [Reader.Reset], but it also
+// fills the buffer with a copy of 'prefix', so that
+// the first bytes read are the bytes in 'prefix',
+// followed by the bytes read from 'rd'. The buffer
+// grows if it is smaller than 'prefix'. Offsets
+// count from the start of 'rd', so [Reader.Offset]
+// starts at -len(prefix), and skipping or seeking
+// past the prefix can still use an [io.Seeker].
+func (r *Reader) ResetWith(rd io.Reader, prefix []byte) {
+	r.Reset(rd)
+	if len(prefix) > cap(r.data) {
+		r.data = make([]byte, 0, len(prefix))
+	}
+	r.data = append(r.data, prefix...)
+	r.base = -int64(len(prefix))
+	r.seen = r.base
+}
+
+// ResetSize is like [Reader.Reset], but it
+// also ensures that the buffer size is at
+// least 'n'. The buffer is only reallocated
+// if it is smaller than 'n'.
+func (r *Reader) ResetSize(rd io.Reader, n int) {
+	if n = max(n, minReaderSize); cap(r.data) < n {
+		r.data = make([]byte, 0, n)
+	}
 	r.data = r.data[0:0]
 	r.n = 0
 	r.state = nil
-	if s, ok := rd.(io.Seeker); ok {
-		r.rs = s
-	} else {
-		r.rs = nil
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	r.base = 0
+	r.marks = r.marks[:0]
+	r.limit = -1
+	r.limitSet = -1
+	r.settings = settings{}
+	r.tee = nil
+	r.hash = nil
+	r.setObserving()
+	r.lines = 0
+	r.col = 0
+	r.seen = 0
+	r.reads = 0
+	r.filled = 0
+	r.token = nil
+	r.scanErr = nil
+	r.scanDone = false
+	r.setSource(rd)
+}
+
+// SwapReader replaces the underlying reader with
+// 'rd' without discarding the buffered bytes, for
+// example to continue reading a connection through
+// a TLS client after a plaintext handshake. Unlike
+// [Reader.Reset], it keeps everything else as well:
+// marks, the offset, the limit, the tee and hash,
+// the position, and the counters. Any error from
+// the old reader is cleared.
+//
+// The buffered bytes were read from the old reader,
+// and they are returned before anything is read from
+// 'rd'; bytes that 'rd' returns follow them directly.
+// If the old reader's data must not be read through
+// the new one, the caller should consume exactly the
+// bytes that belong to the old stream before calling
+// SwapReader (see [Reader.Buffered]). The offset keeps
+// counting across the swap, but seeking with
+// [io.SeekStart] or [io.SeekEnd] and [Reader.ReadAt]
+// use positions in 'rd'.
+func (r *Reader) SwapReader(rd io.Reader) {
+	r.state = nil
+	r.setSource(rd)
+}
+
+// setSource sets the underlying reader and
+// captures the optional interfaces it implements
+func (r *Reader) setSource(rd io.Reader) {
+	r.r = rd
+	r.size = -1
+	r.rs, _ = rd.(io.Seeker)
+	r.ra, _ = rd.(io.ReaderAt)
+	r.dl, _ = rd.(readDeadliner)
+	r.rc, _ = rd.(io.Closer)
+}
+
+// Close closes the underlying reader if it is an
+// [io.Closer], so that a *Reader can be used as an
+// [io.ReadCloser] in place of its source. If the
+// underlying reader is not an io.Closer, Close does
+// nothing and returns [ErrNotCloser]. Buffered bytes
+// remain readable after Close.
+func (r *Reader) Close() error {
+	if r.rc == nil {
+		return ErrNotCloser
+	}
+	return r.rc.Close()
+}
+
+// ResetBuf is like [Reader.Reset], but
+// it also replaces the read buffer with 'buf'.
+// As with [NewReaderBuf], 'buf' is not used when
+// it has a smaller capacity than 16.
+func (r *Reader) ResetBuf(rd io.Reader, buf []byte) {
+	if cap(buf) < minReaderSize {
+		buf = make([]byte, 0, minReaderSize)
 	}
+	r.data = buf[:0]
+	r.Reset(rd)
+}
+
+// Release detaches and returns the read buffer
+// (for example, so that it can be returned to
+// a [sync.Pool]) and drops the underlying reader.
+// Any buffered bytes are discarded. After Release,
+// the reader must not be used until it is given a
+// new buffer with [Reader.ResetBuf].
+func (r *Reader) Release() []byte {
+	buf := r.data[:0]
+	r.Reset(nil)
+	r.data = nil
+	return buf
 }
 
+// Underlying returns the underlying reader (for
+// example, to set a deadline on a [net.Conn]).
+// Reading from it directly skips over the bytes
+// that are already buffered and leaves the reader
+// out of sync with the stream, so it should only
+// be used for operations other than reading.
+func (r *Reader) Underlying() io.Reader { return r.r }
+
 // more() does one read on the underlying reader
 func (r *Reader) more() {
+	r.observe()
+
 	// move data backwards so that
-	// the read offset is 0; this way
-	// we can supply the maximum number of
-	// bytes to the reader
-	if r.n != 0 {
-		if r.n < len(r.data) {
-			r.data = r.data[:copy(r.data[0:], r.data[r.n:])]
-		} else {
-			r.data = r.data[:0]
+	// the read offset (or the oldest mark)
+	// is 0; this way we can supply the
+	// maximum number of bytes to the reader
+	r.compact()
+	// every buffered byte is either unread
+	// or pinned by a mark, so we have to grow
+	if len(r.data) == cap(r.data) {
+		size := 2 * cap(r.data)
+		if r.maxBuf > 0 {
+			if cap(r.data) >= r.maxBuf {
+				r.state = ErrBufferLimitExceeded
+				return
+			}
+			size = min(size, r.maxBuf)
 		}
-		r.n = 0
+		r.ensureCap(size - r.n)
+	}
+	end := cap(r.data)
+	if r.ahead > 0 {
+		end = min(end, len(r.data)+r.ahead)
 	}
 	var a int
-	a, r.state = r.r.Read(r.data[len(r.data):cap(r.data)])
-	if a == 0 && r.state == nil {
-		return
-	} else if a > 0 && r.state == io.EOF {
+	for empty := 1; ; empty++ {
+		a, r.state = r.read(r.data[len(r.data):end])
+		if a != 0 || r.state != nil {
+			break
+		}
+		if empty >= r.maxEmptyReads() {
+			r.state = r.emptyReadError()
+			return
+		}
+	}
+	// keep whatever was read even if the
+	// read also returned an error; the
+	// error is reported once those bytes
+	// have been consumed
+	r.data = r.data[:len(r.data)+a]
+	if a > 0 && r.state == io.EOF {
 		// discard the io.EOF if we read more than 0 bytes.
 		// the next call to Read should return io.EOF again.
 		r.state = nil
-	} else if r.state != nil {
+	}
+}
+
+// compact moves the bytes that must be
+// retained to the front of the buffer
+func (r *Reader) compact() {
+	if k := r.keep(); k != 0 {
+		if k < len(r.data) {
+			r.data = r.data[:copy(r.data[0:], r.data[k:])]
+		} else {
+			r.data = r.data[:0]
+		}
+		r.n -= k
+		r.base += int64(k)
+	}
+}
+
+// Compact moves the buffered bytes to the front
+// of the buffer, so that all of the free space in
+// the buffer is available to the next read. Bytes
+// pinned by a mark are retained. The buffer is
+// compacted automatically when it runs out of
+// space, so Compact is only needed to make room
+// ahead of time (for example, before a large
+// [Reader.Peek] that would otherwise reallocate
+// the buffer). It is a no-op if there is nothing
+// to reclaim.
+func (r *Reader) Compact() {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	r.observe()
+	r.compact()
+}
+
+// ShrinkTo reallocates the buffer with a capacity
+// of 'n' bytes (or the number of bytes that must be
+// retained, if that is larger), so that a reader
+// whose buffer grew to accommodate an unusually large
+// read can return to a smaller footprint. Buffered
+// bytes and bytes pinned by a mark are retained. It
+// is a no-op if the buffer is already no larger
+// than 'n'.
+func (r *Reader) ShrinkTo(n int) {
+	n = max(n, minReaderSize)
+	if cap(r.data) <= n {
 		return
 	}
-	r.data = r.data[:len(r.data)+a]
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	r.observe()
+	k := r.keep()
+	old := r.data[k:]
+	r.data = make([]byte, len(old), max(n, len(old)))
+	copy(r.data, old)
+	r.n -= k
+	r.base += int64(k)
+}
+
+// read() does one read on the underlying
+// reader, respecting the limit set by Limit
+func (r *Reader) read(b []byte) (int, error) {
+	if r.limit == 0 {
+		return 0, io.EOF
+	}
+	if r.limit > 0 && int64(len(b)) > r.limit {
+		b = b[:r.limit]
+	}
+	n, err := r.r.Read(b)
+	r.reads++
+	r.filled += int64(n)
+	if r.limit > 0 {
+		r.limit -= int64(n)
+	}
+	return n, err
+}
+
+// Reads returns the number of calls made to
+// the Read method of the underlying reader since
+// the reader was created or last reset, including
+// reads made directly into caller-supplied slices.
+// When [Reader.WriteTo], [Reader.Copy], or
+// [Reader.WriteToBuffered] hands the rest of the
+// stream to another copy routine, the whole transfer
+// counts as one read, since the calls it makes are not
+// visible to the reader. Bytes skipped by seeking do
+// not involve a read.
+func (r *Reader) Reads() int { return r.reads }
+
+// BytesFilled returns the number of bytes returned
+// by the underlying reader since the reader was
+// created or last reset. Together with [Reader.Reads],
+// it gives the average size of the underlying reads.
+func (r *Reader) BytesFilled() int64 { return r.filled }
+
+// Limit limits the number of bytes that
+// will be read from the underlying reader
+// (including bytes skipped with an [io.Seeker])
+// to 'n' more bytes. Once the limit has been
+// reached, the underlying reader is treated as
+// though it returned [io.EOF]. Bytes that are already
+// buffered are not counted against the limit.
+// A negative 'n' removes the limit. [Reader.Reset]
+// also removes the limit.
+func (r *Reader) Limit(n int64) {
+	if n < 0 {
+		n = -1
+	}
+	r.limit = n
+	r.limitSet = n
+}
+
+// Err returns the last error returned by
+// the underlying reader that has not yet been
+// returned by a reader method, without clearing it.
+func (r *Reader) Err() error { return r.state }
+
+// ClearErr clears the error returned by [Reader.Err]
+// so that the next read will try the underlying
+// reader again (for example, after a transient
+// error like [io.ErrNoProgress]).
+func (r *Reader) ClearErr() { r.state = nil }
+
+// SetMaxEmptyReads sets the number of consecutive
+// times a read on the underlying reader may return
+// no bytes and no error before the reader gives up
+// and returns [io.ErrNoProgress]. If 'n' is less than
+// 1, [DefaultMaxEmptyReads] is used. The limit does
+// not apply to the part of the stream that
+// [Reader.WriteTo] or [Reader.Copy] hands to
+// [io.ReaderFrom] or [io.Copy], since those read the
+// underlying reader directly.
+func (r *Reader) SetMaxEmptyReads(n int) {
+	if n < 1 {
+		n = 0
+	}
+	r.maxEmpty = n
+}
+
+func (r *Reader) maxEmptyReads() int {
+	if r.maxEmpty == 0 {
+		return DefaultMaxEmptyReads
+	}
+	return r.maxEmpty
+}
+
+// SetEmptyReadError sets the error returned in
+// place of [io.ErrNoProgress] when the underlying
+// reader returns no bytes and no error too many
+// times in a row (see [Reader.SetMaxEmptyReads]).
+// This allows a source that is only temporarily
+// empty to be told apart from a broken one; like
+// any other error, it is not retained once it has
+// been returned, so the caller may simply try again.
+// If 'err' is nil, [io.ErrNoProgress] is used.
+func (r *Reader) SetEmptyReadError(err error) {
+	r.emptyErr = err
+}
+
+func (r *Reader) emptyReadError() error {
+	if r.emptyErr == nil {
+		return io.ErrNoProgress
+	}
+	return r.emptyErr
+}
+
+// SetMaxTokenSize sets the maximum length of a
+// string returned by [Reader.ReadCString], not
+// including the terminator, or [Reader.ReadVarString],
+// not including the length prefix. If 'n' is less than
+// 1, there is no limit.
+func (r *Reader) SetMaxTokenSize(n int) {
+	if n < 1 {
+		n = 0
+	}
+	r.maxToken = n
 }
 
 // pop error
@@ -139,10 +655,19 @@ func (r *Reader) err() (e error) {
 	return
 }
 
+// SetPromoteEOF sets whether methods that
+// read a fixed number of bytes (like [Reader.Next],
+// [Reader.ReadFull], and [Reader.Skip]) return
+// [io.ErrUnexpectedEOF] when the stream ends
+// early. The default is true; if set to false,
+// those methods return [io.EOF] instead.
+func (r *Reader) SetPromoteEOF(promote bool) { r.plainEOF = !promote }
+
 // pop error; EOF -> io.ErrUnexpectedEOF
+// (unless plainEOF is set)
 func (r *Reader) noEOF() (e error) {
 	e, r.state = r.state, nil
-	if e == io.EOF {
+	if e == io.EOF && !r.plainEOF {
 		e = io.ErrUnexpectedEOF
 	}
 	return
@@ -151,34 +676,378 @@ func (r *Reader) noEOF() (e error) {
 // buffered bytes
 func (r *Reader) buffered() int { return len(r.data) - r.n }
 
+// bypass returns whether bytes may skip
+// the buffer, either by being read directly
+// into a caller's slice or by seeking
+func (r *Reader) bypass() bool {
+	return len(r.marks) == 0 && !r.observing
+}
+
+// Tee causes every byte that is subsequently
+// consumed from the reader (by any method that
+// advances the reader, including [Reader.Skip])
+// to also be written to 'w'. Bytes that are peeked
+// but not consumed are not written, and bytes
+// that are consumed again after moving the reader
+// backwards are only written once. Errors returned
+// by 'w' are returned by the next reader method call.
+// While teeing, reads are never made directly into
+// caller-supplied slices and [Reader.Skip] reads
+// through the buffer rather than seeking.
+// Tee(nil) and [Reader.Reset] disable teeing.
+func (r *Reader) Tee(w io.Writer) {
+	r.tee = w
+	r.seen = max64(r.seen, r.Offset())
+	r.setObserving()
+}
+
+// Hash causes every byte that is subsequently
+// consumed from the reader to be written to 'h',
+// so that a checksum can be computed over a payload
+// as it is parsed. As with [Reader.Tee], bytes that
+// are peeked but not consumed are not hashed, and
+// bytes consumed again after moving the reader
+// backwards are only hashed once. While hashing,
+// [Reader.Skip] reads the skipped bytes through the
+// buffer rather than seeking past them, so that they
+// are hashed too. Hash(nil) and [Reader.Reset]
+// disable hashing.
+func (r *Reader) Hash(h hash.Hash) {
+	r.hash = h
+	r.seen = max64(r.seen, r.Offset())
+	r.setObserving()
+}
+
+// EnablePositionTracking causes the reader to
+// count the lines and columns it consumes, so
+// that they can be reported by [Reader.Position].
+// Tracking costs some work for every byte consumed,
+// so it is disabled by default. While tracking,
+// reads are never made directly into caller-supplied
+// slices and [Reader.Skip] reads through the buffer
+// rather than seeking, so that no lines are missed.
+// Bytes passed over by [Reader.Seek] with [io.SeekStart]
+// or [io.SeekEnd] are not counted.
+func (r *Reader) EnablePositionTracking() {
+	r.track = true
+	r.seen = max64(r.seen, r.Offset())
+	r.setObserving()
+}
+
+// Position returns the 1-based line and column
+// (in bytes) of the reader's position in the stream.
+// It requires [Reader.EnablePositionTracking];
+// only bytes consumed after tracking was enabled
+// are counted. Each byte is counted the first time
+// it is consumed, so moving the reader backwards
+// does not move the position backwards.
+func (r *Reader) Position() (line, col int) {
+	return r.lines + 1, r.col + 1
+}
+
+// observe passes any newly-consumed bytes
+// to the tee, the hash, and the position tracker
+func (r *Reader) observe() {
+	if r.observing {
+		r.observeSlow()
+	}
+}
+
+// setObserving updates r.observing
+// after a change to tee, hash, or track
+func (r *Reader) setObserving() {
+	r.observing = r.tee != nil || r.hash != nil || r.track
+}
+
+func (r *Reader) observeSlow() {
+	start := int(r.seen - r.base)
+	if start >= r.n {
+		return
+	}
+	b := r.data[start:r.n]
+	r.seen = r.Offset()
+	if r.track {
+		if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+			r.lines += bytes.Count(b, []byte{'\n'})
+			r.col = len(b) - i - 1
+		} else {
+			r.col += len(b)
+		}
+	}
+	if r.hash != nil {
+		r.hash.Write(b)
+	}
+	if r.tee != nil {
+		if _, err := r.tee.Write(b); err != nil && r.state == nil {
+			r.state = err
+		}
+	}
+}
+
+// keep returns the index of the first
+// byte in r.data that must be retained
+func (r *Reader) keep() int {
+	k := r.n
+	for _, m := range r.marks {
+		if i := int(m - r.base); i < k {
+			k = i
+		}
+	}
+	return k
+}
+
+// reset discards the buffer and advances
+// the stream position past an additional
+// 'n' bytes that were never buffered
+func (r *Reader) reset(n int) {
+	r.observe()
+	r.base += int64(len(r.data) + n)
+	r.data = r.data[:0]
+	r.n = 0
+}
+
 // Buffered returns the number of bytes currently in the buffer
 func (r *Reader) Buffered() int { return len(r.data) - r.n }
 
 // BufferSize returns the total size of the buffer
 func (r *Reader) BufferSize() int { return cap(r.data) }
 
+// Size is the same as [Reader.BufferSize];
+// it matches [bufio.Reader.Size].
+func (r *Reader) Size() int { return cap(r.data) }
+
+// Cap returns the total size of the buffer.
+// It is identical to [Reader.BufferSize].
+func (r *Reader) Cap() int { return cap(r.data) }
+
+// Available returns the number of bytes that
+// can be read into the buffer before it has to
+// be reallocated. This includes both the free
+// space at the end of the buffer and the space
+// at the front of the buffer occupied by bytes
+// that have already been consumed (and are not
+// pinned by a mark), which is reclaimed by
+// moving the buffered bytes forward before
+// the next read.
+func (r *Reader) Available() int { return cap(r.data) - len(r.data) + r.keep() }
+
+// Len returns the number of bytes remaining in
+// the stream (both buffered and not yet read from
+// the underlying reader) and true, if that number
+// can be determined. It can only be determined when
+// the underlying reader is an [io.Seeker]; the size
+// of the underlying stream is found by seeking to its
+// end the first time Len is called and is assumed
+// not to change afterwards. Otherwise, Len returns
+// (0, false).
+func (r *Reader) Len() (int, bool) {
+	if r.rs == nil {
+		return 0, false
+	}
+	cur, err := r.rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	end, err := r.streamEnd(cur)
+	if err != nil {
+		return 0, false
+	}
+	rest := max64(end-cur, 0)
+	if r.limit >= 0 && rest > r.limit {
+		rest = r.limit
+	}
+	return r.buffered() + int(rest), true
+}
+
+// streamEnd returns the size of the underlying
+// stream, which is found by seeking to its end the
+// first time and then assumed not to change; 'cur'
+// is the current position, which is restored
+func (r *Reader) streamEnd(cur int64) (int64, error) {
+	if r.size < 0 {
+		end, err := r.rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := r.rs.Seek(cur, io.SeekStart); err != nil {
+			// we're lost; don't
+			// pretend otherwise
+			r.state = err
+			return 0, err
+		}
+		r.size = end
+	}
+	return r.size, nil
+}
+
+// Offset returns the position of the reader
+// relative to the start of the underlying stream:
+// the number of bytes it has advanced past since
+// it was created or last [Reader.Reset], less any
+// bytes injected ahead of the stream. It can
+// therefore be negative: after [Reader.ResetWith]
+// it starts at -len(prefix), and [Reader.PushBack]
+// moves it back by the number of bytes pushed back,
+// even past zero.
+func (r *Reader) Offset() int64 { return r.base + int64(r.n) }
+
+// Grow grows the buffer, if necessary, so that
+// it has room for another 'n' bytes in addition
+// to the bytes that are currently buffered. After
+// Grow(n), at least 'n' bytes can be read into the
+// buffer without another allocation. If 'n' is
+// negative, Grow will panic. If the buffer would
+// grow beyond the limit set by [Reader.SetMaxBufferSize],
+// it is not grown, and [ErrBufferLimitExceeded] is
+// returned by the next call that has to read from
+// the underlying reader.
+func (r *Reader) Grow(n int) {
+	if n < 0 {
+		panic("fwd.Reader.Grow: negative count")
+	}
+	if err := r.ensureCap(r.buffered() + n); err != nil && r.state == nil {
+		r.state = err
+	}
+}
+
+// ensureCap reallocates the buffer, if necessary,
+// so that it can hold 'n' bytes past the read offset
+// once it has been compacted. The new buffer holds
+// exactly that many bytes plus any bytes pinned by
+// a mark, and the retained bytes are moved to the
+// front of it, so r.n is 0 unless a mark is outstanding.
+func (r *Reader) ensureCap(n int) error {
+	need := n + r.n - r.keep()
+	if need <= cap(r.data) {
+		return nil
+	}
+	if r.maxBuf > 0 && need > r.maxBuf {
+		return ErrBufferLimitExceeded
+	}
+	k := r.keep()
+	old := r.data[k:]
+	r.data = make([]byte, len(old), need)
+	copy(r.data, old)
+	r.n -= k
+	r.base += int64(k)
+	// the unread byte or rune
+	// may not have been retained
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	return nil
+}
+
+// SetPeekGrowsBuffer determines whether [Reader.Peek]
+// reallocates the buffer when 'n' is larger than it.
+// If 'grow' is false, such a Peek returns the buffered
+// bytes and [ErrBufferFull] instead, so the buffer
+// stays at its initial size. Peek grows the buffer
+// by default.
+func (r *Reader) SetPeekGrowsBuffer(grow bool) {
+	r.noGrow = !grow
+}
+
+// SetMaxBufferSize limits the size to which the
+// buffer may grow. Methods that would need a larger
+// buffer (for example, a [Reader.Peek] of more bytes
+// than the limit, or a [Reader.PeekUntil] whose delimiter
+// is further away) return [ErrBufferLimitExceeded]
+// instead. If 'n' is less than 1, there is no limit,
+// which is the default. The limit does not shrink a
+// buffer that is already larger.
+func (r *Reader) SetMaxBufferSize(n int) {
+	if n < 1 {
+		n = 0
+	}
+	r.maxBuf = n
+}
+
+// PeekAll returns all of the buffered bytes
+// without reading from the underlying reader
+// and without advancing the reader. Unlike
+// Peek(r.Buffered()), PeekAll never performs I/O.
+// The returned slice is only valid until the
+// next reader method call.
+func (r *Reader) PeekAll() []byte { return r.data[r.n:] }
+
+// PeekAvailable returns up to 'n' of the bytes
+// that are already buffered, without performing
+// any I/O and without advancing the reader, and
+// reports whether all 'n' bytes were available.
+// The returned slice is only valid until the next
+// reader method call. A negative 'n' is treated
+// as zero.
+func (r *Reader) PeekAvailable(n int) ([]byte, bool) {
+	n = max(n, 0)
+	if n > r.buffered() {
+		return r.data[r.n:], false
+	}
+	return r.data[r.n : r.n+n], true
+}
+
+// Fill performs a single read on the underlying
+// reader to fill the free space in the buffer,
+// returning any error encountered (including [io.EOF]).
+// Fill is a no-op if the buffer is already full.
+func (r *Reader) Fill() error {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if r.buffered() == cap(r.data) {
+		return nil
+	}
+	if r.state == nil {
+		r.more()
+	}
+	return r.err()
+}
+
 // Peek returns the next 'n' buffered bytes,
 // reading from the underlying reader if necessary.
 // It will only return a slice shorter than 'n' bytes
-// if it also returns an error. Peek does not advance
-// the reader. EOF errors are *not* returned as
-// io.ErrUnexpectedEOF.
+// if it also returns an error, in which case the slice
+// holds all of the bytes that were buffered, so its
+// length is the number of bytes actually available.
+// Peek does not advance the reader. EOF errors are
+// *not* returned as io.ErrUnexpectedEOF; see
+// [Reader.PeekUpTo] for a Peek that treats the end
+// of the stream as success. Peek(0) returns an empty
+// slice and a nil error, and a negative 'n' returns
+// [bufio.ErrNegativeCount].
 func (r *Reader) Peek(n int) ([]byte, error) {
+	return r.PeekContext(context.Background(), n)
+}
+
+// PeekContext is like [Reader.Peek], but it
+// returns early with the context's error
+// if 'ctx' is done before 'n' bytes have been
+// buffered. The context is checked between reads
+// on the underlying reader; a read that is already
+// in progress is not interrupted.
+func (r *Reader) PeekContext(ctx context.Context, n int) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if n < 0 {
+		return nil, bufio.ErrNegativeCount
+	}
+
 	// in the degenerate case,
 	// we may need to realloc
 	// (the caller asked for more
 	// bytes than the size of the buffer)
-	if cap(r.data) < n {
-		old := r.data[r.n:]
-		r.data = make([]byte, n+r.buffered())
-		r.data = r.data[:copy(r.data, old)]
-		r.n = 0
+	if r.noGrow && n+r.n-r.keep() > cap(r.data) {
+		return r.data[r.n:], ErrBufferFull
+	}
+	if err := r.ensureCap(n); err != nil {
+		return r.data[r.n:], err
 	}
 
 	// keep filling until
 	// we hit an error or
 	// read enough bytes
 	for r.buffered() < n && r.state == nil {
+		if err := ctx.Err(); err != nil {
+			return r.data[r.n:], err
+		}
 		r.more()
 	}
 
@@ -190,16 +1059,222 @@ func (r *Reader) Peek(n int) ([]byte, error) {
 	return r.data[r.n : r.n+n], nil
 }
 
+// AtEOF reports whether the reader has reached
+// the end of the stream, reading from the
+// underlying reader only if the buffer is empty.
+// It does not advance the reader. The io.EOF is
+// kept, so subsequent reads return it as well.
+// Any other error is returned with 'false' so
+// that it can be told apart from the end of the
+// stream.
+func (r *Reader) AtEOF() (bool, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if r.buffered() == 0 && r.state == nil {
+		r.more()
+	}
+	if r.buffered() > 0 {
+		return false, nil
+	}
+	if r.state == io.EOF {
+		return true, nil
+	}
+	return false, r.err()
+}
+
+// PeekUpTo is like [Reader.Peek], but reaching the
+// end of the stream before 'n' bytes are buffered is
+// not an error: it returns the bytes that remain and
+// a nil error. A non-nil error always means that
+// something other than the end of the stream cut the
+// slice short. The io.EOF is kept, so the next read
+// returns it.
+func (r *Reader) PeekUpTo(n int) ([]byte, error) {
+	p, err := r.Peek(n)
+	if err == io.EOF {
+		r.state = io.EOF
+		err = nil
+	}
+	return p, err
+}
+
+// PeekN is like [Reader.Peek], but it returns the
+// peeked bytes split into consecutive fields of the
+// given sizes, all of which alias the buffer and are
+// only valid until the next reader method call. If the
+// stream ends first, PeekN returns the fields that
+// were complete and the error encountered. A negative
+// size returns [bufio.ErrNegativeCount].
+func (r *Reader) PeekN(sizes ...int) ([][]byte, error) {
+	total := 0
+	for _, n := range sizes {
+		if n < 0 {
+			return nil, bufio.ErrNegativeCount
+		}
+		total += n
+	}
+	b, err := r.Peek(total)
+	fields := make([][]byte, 0, len(sizes))
+	for _, n := range sizes {
+		if len(b) < n {
+			break
+		}
+		fields = append(fields, b[:n:n])
+		b = b[n:]
+	}
+	return fields, err
+}
+
+// readDeadliner is implemented by
+// readers with read deadlines, like net.Conn
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// PeekDeadline is like [Reader.Peek], but if more
+// bytes have to be read, it first sets the read
+// deadline of the underlying reader (typically a
+// [net.Conn]) to 't', so that a slow peer cannot make
+// it wait indefinitely; the error for a missed deadline
+// is returned like any other read error. The deadline
+// is cleared before PeekDeadline returns. If the
+// underlying reader has no SetReadDeadline method,
+// PeekDeadline returns [ErrNoDeadline].
+func (r *Reader) PeekDeadline(n int, t time.Time) ([]byte, error) {
+	if r.dl == nil {
+		return nil, ErrNoDeadline
+	}
+	if n <= r.buffered() {
+		return r.Peek(n)
+	}
+	if err := r.dl.SetReadDeadline(t); err != nil {
+		return nil, err
+	}
+	b, err := r.Peek(n)
+	if derr := r.dl.SetReadDeadline(time.Time{}); derr != nil && err == nil {
+		err = derr
+	}
+	return b, err
+}
+
+// PeekUntil returns the buffered bytes up to and
+// including the first occurrence of 'delim',
+// reading from the underlying reader and growing
+// the buffer as necessary. PeekUntil does not advance
+// the reader, and the returned slice is only valid
+// until the next reader method call. If the delimiter
+// is not found, PeekUntil returns all of the buffered
+// bytes and the error encountered. EOF errors are
+// *not* returned as io.ErrUnexpectedEOF.
+func (r *Reader) PeekUntil(delim byte) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if i := r.indexByte(delim); i >= 0 {
+		return r.data[r.n : r.n+i+1], nil
+	}
+	return r.data[r.n:], r.err()
+}
+
+// PeekWhile returns the longest run of buffered
+// bytes, starting at the current position, for
+// which 'pred' returns true, reading from the
+// underlying reader and growing the buffer as the
+// run extends. PeekWhile does not advance the reader,
+// and the returned slice is only valid until the next
+// reader method call. If the stream ends during the run,
+// PeekWhile returns the run along with the error
+// encountered (usually [io.EOF]).
+func (r *Reader) PeekWhile(pred func(byte) bool) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	scanned := 0
+	for {
+		for i, c := range r.data[r.n+scanned:] {
+			if !pred(c) {
+				return r.data[r.n : r.n+scanned+i], nil
+			}
+		}
+		scanned = r.buffered()
+		if r.state != nil {
+			return r.data[r.n:], r.err()
+		}
+		r.more()
+	}
+}
+
+// IndexByte returns the offset of the next
+// occurrence of 'c' relative to the current
+// position in the stream, reading from the
+// underlying reader and growing the buffer
+// as necessary. IndexByte does not advance the
+// reader. If 'c' is not found, IndexByte returns
+// -1 and the error encountered (usually [io.EOF]).
+func (r *Reader) IndexByte(c byte) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if i := r.indexByte(c); i >= 0 {
+		return i, nil
+	}
+	return -1, r.err()
+}
+
+// IndexPattern is like [Reader.IndexByte], but it
+// returns the offset of the next occurrence of the
+// byte sequence 'pat'. An occurrence that straddles
+// the bytes buffered before and after a read from the
+// underlying reader is found like any other. If 'pat'
+// is empty, IndexPattern returns 0.
+func (r *Reader) IndexPattern(pat []byte) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	scanned := 0
+	for {
+		if i := bytes.Index(r.data[r.n+scanned:], pat); i >= 0 {
+			return scanned + i, nil
+		}
+		// the last len(pat)-1 bytes may be
+		// the start of a match that the next
+		// read will complete
+		scanned = max(r.buffered()-len(pat)+1, 0)
+		if r.state != nil {
+			return -1, r.err()
+		}
+		r.more()
+	}
+}
+
+// indexByte(c) fills the buffer until it
+// contains 'c' and returns its offset from r.n,
+// or returns -1 if an error was encountered first
+func (r *Reader) indexByte(c byte) int {
+	scanned := 0
+	for {
+		if i := bytes.IndexByte(r.data[r.n+scanned:], c); i >= 0 {
+			return scanned + i
+		}
+		scanned = r.buffered()
+		if r.state != nil {
+			return -1
+		}
+		r.more()
+	}
+}
+
 // discard(n) discards up to 'n' buffered bytes, and
 // and returns the number of bytes discarded
 func (r *Reader) discard(n int) int {
 	inbuf := r.buffered()
 	if inbuf <= n {
-		r.n = 0
-		r.data = r.data[:0]
+		r.n = len(r.data)
+		if len(r.marks) > 0 {
+			r.observe()
+		} else {
+			r.reset(0)
+		}
 		return inbuf
 	}
 	r.n += n
+	r.observe()
 	return n
 }
 
@@ -207,35 +1282,404 @@ func (r *Reader) discard(n int) int {
 // Returns the number of bytes skipped and any
 // errors encountered. It is analogous to Seek(n, 1).
 // If the underlying reader implements io.Seeker, then
-// that method will be used to skip forward.
+// that method will be used to skip forward, unless
+// the skip extends only a little way past the buffered
+// data (see [Reader.SetSeekThreshold]).
+//
+// A negative 'n' moves the reader backwards. If the
+// bytes are still in the buffer, no I/O is performed;
+// otherwise the underlying [io.Seeker] is used, and
+// if there isn't one (or a mark is outstanding),
+// Skip returns [ErrNoRewind].
 //
 // If the reader encounters
 // an EOF before skipping 'n' bytes, it
-// returns [io.ErrUnexpectedEOF]. If the
-// underlying reader implements [io.Seeker], then
-// those rules apply instead. (Many implementations
-// will not return [io.EOF] until the next call
-// to Read).
+// returns [io.ErrUnexpectedEOF]. This holds when
+// the underlying [io.Seeker] is used, too: since
+// many implementations will seek past the end
+// without an error, the size of the stream is
+// found (and cached, as with [Reader.Len]) by
+// seeking to its end first.
+//
+// Skip(0) does nothing and returns a nil error.
 func (r *Reader) Skip(n int) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
 	if n < 0 {
-		return 0, os.ErrInvalid
-	}
-
-	// discard some or all of the current buffer
-	skipped := r.discard(n)
-
-	// if we can Seek() through the remaining bytes, do that
-	if n > skipped && r.rs != nil {
-		nn, err := r.rs.Seek(int64(n-skipped), 1)
-		return int(nn) + skipped, err
+		return r.skipBack(-n)
 	}
-	// otherwise, keep filling the buffer
-	// and discarding it up to 'n'
-	for skipped < n && r.state == nil {
-		r.more()
-		skipped += r.discard(n - skipped)
+	if n == 0 {
+		return 0, nil
 	}
-	return skipped, r.noEOF()
+	return r.skip(n, r.noEOF)
+}
+
+// Discard moves the reader forward 'n' bytes
+// and returns the number of bytes discarded.
+// It is identical to [Reader.Skip], except that
+// it returns [io.EOF] rather than [io.ErrUnexpectedEOF]
+// if the stream ends before 'n' bytes have been discarded,
+// and a negative 'n' returns [bufio.ErrNegativeCount].
+func (r *Reader) Discard(n int) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if n < 0 {
+		return 0, bufio.ErrNegativeCount
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return r.skip(n, r.err)
+}
+
+// Drain moves the reader to the end of the
+// stream and returns the number of bytes passed
+// over. If the underlying reader is an [io.Seeker],
+// Drain seeks to its end (subject to the same
+// conditions as [Reader.Skip]); otherwise, it reads
+// and discards the rest of the stream through the
+// buffer. Reaching the end of the stream is the point,
+// so Drain returns a nil error rather than [io.EOF].
+func (r *Reader) Drain() (int64, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	n := int64(r.discard(r.buffered()))
+	if r.rs != nil && r.bypass() && r.state == nil {
+		if pos, err := r.rs.Seek(0, io.SeekCurrent); err == nil {
+			end, err := r.rs.Seek(0, io.SeekEnd)
+			if err != nil {
+				return n, err
+			}
+			rest := max64(end-pos, 0)
+			if r.limit >= 0 && rest > r.limit {
+				// stop at the limit
+				rest = r.limit
+				if _, err := r.rs.Seek(pos+rest, io.SeekStart); err != nil {
+					return n, err
+				}
+			}
+			r.reset(int(rest))
+			if r.limit >= 0 {
+				r.limit -= rest
+			}
+			return n + rest, nil
+		}
+	}
+	for r.state == nil {
+		r.more()
+		n += int64(r.discard(r.buffered()))
+	}
+	if err := r.err(); err != io.EOF {
+		return n, err
+	}
+	return n, nil
+}
+
+// SetSeekThreshold sets the smallest number of
+// bytes beyond the buffered data that [Reader.Skip]
+// and [Reader.Discard] will pass over by seeking the
+// underlying [io.Seeker]. Shorter skips read through
+// the buffer instead, which keeps the bytes read
+// along the way available to subsequent reads. If
+// 'n' is less than 1, the threshold is the size of
+// the buffer, which is the default.
+func (r *Reader) SetSeekThreshold(n int) {
+	if n < 1 {
+		n = 0
+	}
+	r.seekMin = n
+}
+
+func (r *Reader) seekThreshold() int {
+	if r.seekMin == 0 {
+		return max(cap(r.data), 1)
+	}
+	return r.seekMin
+}
+
+// SetReadAhead sets the largest number of bytes
+// requested from the underlying reader by a single
+// fill of the buffer. Requests for more than 'n'
+// bytes, like [Reader.Peek] with a larger argument,
+// are satisfied by several reads. Bounding the fill
+// size keeps a small request on a large buffer from
+// waiting on a slow source for bytes it does not
+// need yet. If 'n' is less than 1, each fill asks
+// for as much as the buffer can hold, which is the
+// default.
+func (r *Reader) SetReadAhead(n int) {
+	if n < 1 {
+		n = 0
+	}
+	r.ahead = n
+}
+
+// skip(n) moves the reader forward 'n' bytes
+// and uses pop() to surface the read error
+func (r *Reader) skip(n int, pop func() error) (int, error) {
+	// discard some or all of the current buffer
+	skipped := r.discard(n)
+
+	// if we can Seek() through the remaining bytes, do that
+	// (unless a mark needs them to stay buffered, or there
+	// are so few of them that reading them is cheaper)
+	if n-skipped >= r.seekThreshold() && r.rs != nil && r.bypass() {
+		s := n - skipped
+		if r.limit >= 0 && int64(s) > r.limit {
+			// we can only go as far as the limit
+			s = int(r.limit)
+			r.state = io.EOF
+		}
+		s, err := r.skipSeek(s)
+		if err != nil {
+			return skipped + s, err
+		}
+		return skipped + s, pop()
+	}
+	// otherwise, keep filling the buffer
+	// and discarding it up to 'n'
+	for skipped < n && r.state == nil {
+		r.more()
+		skipped += r.discard(n - skipped)
+	}
+	if skipped == n {
+		// any error that arrived along with
+		// the last bytes stays pending
+		return skipped, nil
+	}
+	return skipped, pop()
+}
+
+// skipSeek seeks the underlying reader forward
+// past 'n' bytes beyond the (empty) buffer and
+// returns the distance actually moved, which is
+// short if the stream ends first. Many seekers
+// (like [os.File]) happily seek past the end, so
+// the end of the stream is checked beforehand.
+func (r *Reader) skipSeek(n int) (int, error) {
+	pre, err := r.rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if end, err := r.streamEnd(pre); err == nil {
+		if pre+int64(n) > end {
+			n = int(max64(end-pre, 0))
+			r.state = io.EOF
+		}
+	} else if r.state != nil {
+		// lost our place finding the end
+		return 0, r.err()
+	}
+	post, err := r.rs.Seek(int64(n), io.SeekCurrent)
+	if err == nil && post > pre+int64(n) {
+		// the seeker overshot; go back
+		// to where we meant to be
+		post, err = r.rs.Seek(pre+int64(n), io.SeekStart)
+	}
+	if err != nil {
+		// find out where the
+		// underlying reader ended up
+		var err2 error
+		if post, err2 = r.rs.Seek(0, io.SeekCurrent); err2 != nil || post < pre {
+			return 0, err
+		}
+	} else if post < pre+int64(n) {
+		// the seeker stopped short,
+		// so we're at the end of the stream
+		r.state = io.EOF
+	}
+	s := int(post - pre)
+	r.reset(s)
+	if r.limit >= 0 {
+		r.limit -= int64(s)
+	}
+	return s, err
+}
+
+// skipBack moves the reader back 'n' bytes
+func (r *Reader) skipBack(n int) (int, error) {
+	if n <= r.n {
+		r.n -= n
+		return -n, nil
+	}
+	if r.rs == nil || len(r.marks) > 0 {
+		return 0, ErrNoRewind
+	}
+	// the underlying reader is positioned
+	// after the buffered bytes
+	pos := r.Offset() - int64(n)
+	if _, err := r.rs.Seek(-int64(n+r.buffered()), io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	r.data = r.data[:0]
+	r.n = 0
+	r.base = pos
+	r.state = nil
+	return -n, nil
+}
+
+// SkipWhile moves the reader forward past every
+// leading byte for which 'pred' returns true, and
+// returns the number of bytes skipped. The first
+// byte for which 'pred' returns false is left in the
+// buffer. If the stream ends first, SkipWhile returns
+// the error encountered (usually [io.EOF]).
+func (r *Reader) SkipWhile(pred func(byte) bool) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	skipped := 0
+	for {
+		for i, c := range r.data[r.n:] {
+			if !pred(c) {
+				skipped += r.discard(i)
+				return skipped, nil
+			}
+		}
+		skipped += r.discard(r.buffered())
+		if r.state != nil {
+			return skipped, r.err()
+		}
+		r.more()
+	}
+}
+
+// Seek implements [io.Seeker].
+//
+// Seeks relative to [io.SeekCurrent] are
+// equivalent to [Reader.Skip], so they may be used
+// on any reader. Other seeks are passed through to
+// the underlying [io.Seeker] and discard the buffer;
+// afterwards, [Reader.Offset] is relative to the start
+// of the underlying stream. If the underlying reader
+// is not an [io.Seeker], Seek returns [ErrNotSeeker].
+// Seeks that would discard the buffer are not
+// permitted while a mark is outstanding.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent {
+		if _, err := r.Skip(int(offset)); err != nil {
+			return 0, err
+		}
+		if r.rs == nil {
+			return r.Offset(), nil
+		}
+		pos, err := r.rs.Seek(0, io.SeekCurrent)
+		return pos - int64(r.buffered()), err
+	}
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if r.rs == nil {
+		return 0, ErrNotSeeker
+	}
+	if len(r.marks) > 0 {
+		return 0, ErrNoRewind
+	}
+	pos, err := r.rs.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	r.data = r.data[:0]
+	r.n = 0
+	r.base = pos
+	r.state = nil
+	r.seen = max64(r.seen, pos)
+	return pos, nil
+}
+
+// Consume reports whether the next bytes in
+// the stream are equal to 'prefix', and if so,
+// advances the reader past them. Otherwise, the
+// reader is not advanced. If the stream ends before
+// len(prefix) bytes, Consume returns false and
+// the error encountered (usually [io.EOF]).
+func (r *Reader) Consume(prefix []byte) (bool, error) {
+	b, err := r.Peek(len(prefix))
+	if len(b) < len(prefix) {
+		return false, err
+	}
+	if !bytes.Equal(b, prefix) {
+		return false, nil
+	}
+	r.discard(len(prefix))
+	if len(prefix) > 0 {
+		r.lastByte = int(prefix[len(prefix)-1])
+	}
+	return true, nil
+}
+
+// SkipUntil moves the reader forward past the
+// next occurrence of 'delim', including the
+// delimiter itself, and returns the number of
+// bytes skipped. If the reader encounters an EOF
+// before finding the delimiter, it skips all of
+// the remaining bytes and returns [io.ErrUnexpectedEOF].
+func (r *Reader) SkipUntil(delim byte) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	skipped := 0
+	for {
+		if i := bytes.IndexByte(r.data[r.n:], delim); i >= 0 {
+			skipped += r.discard(i + 1)
+			return skipped, nil
+		}
+		skipped += r.discard(r.buffered())
+		if r.state != nil {
+			return skipped, r.noEOF()
+		}
+		r.more()
+	}
+}
+
+// ReadAt implements [io.ReaderAt] by reading
+// directly from the underlying reader, which must
+// itself be an [io.ReaderAt]; otherwise ReadAt
+// returns [ErrNotReaderAt]. 'off' is interpreted by
+// the underlying reader, and is not relative to
+// [Reader.Offset]. ReadAt neither uses nor disturbs
+// the buffer or the current position in the stream.
+func (r *Reader) ReadAt(b []byte, off int64) (int, error) {
+	if r.ra == nil {
+		return 0, ErrNotReaderAt
+	}
+	return r.ra.ReadAt(b, off)
+}
+
+// PeekAt is like [Reader.ReadAt], but it also
+// works when the underlying reader is only an
+// [io.Seeker]: it seeks to 'off', reads, and then
+// seeks back, so the buffer and the current position
+// in the stream are not disturbed. As with ReadAt,
+// 'off' is interpreted by the underlying reader, and
+// PeekAt returns [io.EOF] if the stream ends before
+// 'p' has been filled. If the underlying reader is
+// neither an [io.ReaderAt] nor an [io.Seeker], PeekAt
+// returns [ErrNotSeeker].
+func (r *Reader) PeekAt(p []byte, off int64) (int, error) {
+	if r.ra != nil {
+		return r.ra.ReadAt(p, off)
+	}
+	if r.rs == nil {
+		return 0, ErrNotSeeker
+	}
+	pos, err := r.rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if _, serr := r.rs.Seek(pos, io.SeekStart); serr != nil {
+		// the buffer no longer matches
+		// the underlying reader
+		r.state = serr
+		if err == nil {
+			err = serr
+		}
+	}
+	return n, err
 }
 
 // Next returns the next 'n' bytes in the stream.
@@ -247,13 +1691,18 @@ func (r *Reader) Skip(n int) (int, error) {
 // If an the returned slice is less than the
 // length asked for, an error will be returned,
 // and the reader position will not be incremented.
+// Next(0) returns an empty slice and a nil error,
+// and a negative 'n' returns [bufio.ErrNegativeCount].
 func (r *Reader) Next(n int) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if n < 0 {
+		return nil, bufio.ErrNegativeCount
+	}
+
 	// in case the buffer is too small
-	if cap(r.data) < n {
-		old := r.data[r.n:]
-		r.data = make([]byte, n+r.buffered())
-		r.data = r.data[:copy(r.data, old)]
-		r.n = 0
+	if err := r.ensureCap(n); err != nil {
+		return r.data[r.n:], err
 	}
 
 	// fill at least 'n' bytes
@@ -266,66 +1715,274 @@ func (r *Reader) Next(n int) ([]byte, error) {
 	}
 	out := r.data[r.n : r.n+n]
 	r.n += n
+	r.observe()
+	if n > 0 {
+		r.lastByte = int(out[n-1])
+	}
 	return out, nil
 }
 
 // Read implements [io.Reader].
+//
+// Buffered bytes are always returned before
+// any error from the underlying reader; the
+// error is returned by the first call to Read
+// that finds the buffer empty.
 func (r *Reader) Read(b []byte) (int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+
 	// if we have data in the buffer, just
 	// return that.
 	if r.buffered() != 0 {
 		x := copy(b, r.data[r.n:])
 		r.n += x
+		r.observe()
+		if x > 0 {
+			r.lastByte = int(b[x-1])
+		}
 		return x, nil
 	}
+	// an error that arrived with the bytes
+	// just consumed is reported now
+	if r.state != nil {
+		return 0, r.err()
+	}
 	var n int
 	// we have no buffered data; determine
 	// whether or not to buffer or call
 	// the underlying reader directly
-	if len(b) >= cap(r.data) {
-		n, r.state = r.r.Read(b)
+	if len(b) >= cap(r.data) && r.bypass() {
+		n, r.state = r.read(b)
+		r.reset(n)
 	} else {
 		r.more()
-		n = copy(b, r.data)
-		r.n = n
+		n = copy(b, r.data[r.n:])
+		r.n += n
+		r.observe()
 	}
 	if n == 0 {
 		return 0, r.err()
 	}
+	r.lastByte = int(b[n-1])
 	return n, nil
 }
 
 // ReadFull attempts to read len(b) bytes into
 // 'b'. It returns the number of bytes read into
 // 'b', and an error if it does not return len(b).
-// EOF is considered an unexpected error.
+// Like [io.ReadFull], it returns [io.EOF] if the
+// stream ends before any bytes are read, and
+// [io.ErrUnexpectedEOF] if it ends after some
+// but not all of the bytes have been read.
 func (r *Reader) ReadFull(b []byte) (int, error) {
+	return r.ReadFullContext(context.Background(), b)
+}
+
+// ReadFullContext is like [Reader.ReadFull], but
+// it returns early with the context's error if 'ctx'
+// is done before len(b) bytes have been read. The
+// context is checked between reads on the underlying
+// reader; a read that is already in progress is
+// not interrupted.
+func (r *Reader) ReadFullContext(ctx context.Context, b []byte) (int, error) {
 	var n int  // read into b
 	var nn int // scratch
 	l := len(b)
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if l <= r.buffered() {
+		// everything is already buffered
+		r.n += copy(b, r.data[r.n:])
+		r.observe()
+		if l > 0 {
+			r.lastByte = int(b[l-1])
+		}
+		return l, nil
+	}
 	// either read buffered data,
 	// or read directly for the underlying
 	// buffer, or fetch more buffered data.
-	for n < l && r.state == nil {
+	// A pending error only ends the loop once
+	// the buffered bytes have been copied.
+	for n < l {
 		if r.buffered() != 0 {
 			nn = copy(b[n:], r.data[r.n:])
 			n += nn
 			r.n += nn
-		} else if l-n > cap(r.data) {
-			nn, r.state = r.r.Read(b[n:])
+		} else if r.state != nil {
+			break
+		} else if err := ctx.Err(); err != nil {
+			r.observe()
+			return n, err
+		} else if l-n > cap(r.data) && r.bypass() {
+			nn, r.state = r.read(b[n:])
 			n += nn
+			r.reset(nn)
 		} else {
 			r.more()
 		}
 	}
+	r.observe()
 	if n < l {
+		if n == 0 {
+			// like io.ReadFull, a stream that
+			// ends before any bytes are read
+			// returns a plain io.EOF
+			return 0, r.err()
+		}
 		return n, r.noEOF()
 	}
+	if n > 0 {
+		r.lastByte = int(b[n-1])
+	}
 	return n, nil
 }
 
+// ReadFixed reads exactly 'n' bytes, appends them
+// to 'dst', and returns the extended slice. Unlike
+// the slice returned by [Reader.Next], the result
+// belongs to the caller, and no allocation is made
+// if 'dst' already has room for 'n' more bytes;
+// otherwise, room is added as the bytes arrive, so
+// a large 'n' does not allocate much more than the
+// stream actually holds. Like [Reader.Next], it
+// returns [io.ErrUnexpectedEOF] if the stream ends
+// first, along with 'dst' extended by the bytes
+// that were read. A negative 'n' returns
+// [bufio.ErrNegativeCount].
+func (r *Reader) ReadFixed(n int, dst []byte) ([]byte, error) {
+	if n < 0 {
+		return dst, bufio.ErrNegativeCount
+	}
+	l := len(dst)
+	for len(dst)-l < n {
+		rest := n - (len(dst) - l)
+		if len(dst) == cap(dst) {
+			// grow by no more than has been read
+			// so far (or a chunk), so that a bogus
+			// 'n' cannot allocate much more than
+			// the stream actually delivers
+			grow := min(rest, max(len(dst)-l, max(r.buffered(), readChunk)))
+			grown := make([]byte, len(dst), len(dst)+grow)
+			copy(grown, dst)
+			dst = grown
+		}
+		nn, err := r.ReadFull(dst[len(dst) : len(dst)+min(cap(dst)-len(dst), rest)])
+		dst = dst[:len(dst)+nn]
+		if err != nil {
+			if err == io.EOF && !r.plainEOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return dst, err
+		}
+	}
+	return dst, nil
+}
+
+// ReadBlock reads exactly 'n' bytes into a newly
+// allocated slice, which, unlike the slice returned
+// by [Reader.Next], may be retained. Like Next, it
+// returns [io.ErrUnexpectedEOF] along with the bytes
+// that were read if the stream ends first.
+func (r *Reader) ReadBlock(n int) ([]byte, error) {
+	return r.ReadFixed(n, nil)
+}
+
+// ReadStringN reads exactly 'n' bytes and returns
+// them as a string, for example to read a string
+// whose length has already been read. Like
+// [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// along with the bytes that were read if the stream
+// ends first. A negative 'n' returns [bufio.ErrNegativeCount].
+func (r *Reader) ReadStringN(n int) (string, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if n < 0 {
+		return "", bufio.ErrNegativeCount
+	}
+	// the builder grows as bytes arrive, so a
+	// bogus 'n' cannot allocate much more than
+	// the stream actually delivers
+	var sb strings.Builder
+	sb.Grow(min(n, max(r.buffered(), readChunk)))
+	for sb.Len() < n {
+		if r.buffered() == 0 {
+			if r.state != nil {
+				return sb.String(), r.noEOF()
+			}
+			r.more()
+			continue
+		}
+		c := min(n-sb.Len(), r.buffered())
+		sb.Write(r.data[r.n : r.n+c])
+		r.discard(c)
+	}
+	return sb.String(), nil
+}
+
+// ReadAtLeast reads into 'b' until it has read
+// at least 'min' bytes, with the same semantics as
+// [io.ReadAtLeast]: it returns [io.EOF] if no bytes
+// were read and [io.ErrUnexpectedEOF] if the stream
+// ended after fewer than 'min' bytes were read.
+// Buffered bytes are copied into 'b' before any
+// reads are made on the underlying reader.
+func (r *Reader) ReadAtLeast(b []byte, min int) (int, error) {
+	if len(b) < min {
+		return 0, io.ErrShortBuffer
+	}
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	n := copy(b, r.data[r.n:])
+	r.n += n
+	for n < min && r.state == nil {
+		if len(b)-n >= cap(r.data) && r.bypass() {
+			nn, err := r.read(b[n:])
+			r.state = err
+			n += nn
+			r.reset(nn)
+		} else {
+			r.more()
+			nn := copy(b[n:], r.data[r.n:])
+			r.n += nn
+			n += nn
+		}
+	}
+	r.observe()
+	if n > 0 {
+		r.lastByte = int(b[n-1])
+	}
+	if n >= min {
+		return n, nil
+	}
+	err := r.err()
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
 // ReadByte implements [io.ByteReader].
 func (r *Reader) ReadByte() (byte, error) {
+	// fast path: the byte is already buffered
+	// and nothing needs to observe it. (This
+	// cannot be made small enough to inline:
+	// the call to readByteSlow alone costs
+	// most of the compiler's inlining budget.)
+	if r.n < len(r.data) && !r.observing {
+		b := r.data[r.n]
+		r.n++
+		r.lastByte = int(b)
+		r.lastRuneSize = -1
+		return b, nil
+	}
+	return r.readByteSlow()
+}
+
+func (r *Reader) readByteSlow() (byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
 	for r.buffered() < 1 && r.state == nil {
 		r.more()
 	}
@@ -334,46 +1991,749 @@ func (r *Reader) ReadByte() (byte, error) {
 	}
 	b := r.data[r.n]
 	r.n++
+	r.observe()
+	r.lastByte = int(b)
 	return b, nil
 }
 
-// WriteTo implements [io.WriterTo].
-func (r *Reader) WriteTo(w io.Writer) (int64, error) {
-	var (
-		i   int64
-		ii  int
-		err error
-	)
-	// first, clear buffer
-	if r.buffered() > 0 {
-		ii, err = w.Write(r.data[r.n:])
-		i += int64(ii)
-		if err != nil {
-			return i, err
+// PushBack inserts 'b' into the stream at the
+// current position, so that the next bytes read
+// are the bytes in 'b', followed by the bytes
+// that were already buffered and then the rest of
+// the underlying stream. The pushed-back bytes take
+// the place of the last len(b) bytes consumed, so
+// [Reader.Offset] moves back by len(b), and rewinding
+// to a mark before the current position yields the
+// pushed-back bytes rather than the original ones.
+// If making room for 'b' would grow the buffer
+// beyond the limit set by [Reader.SetMaxBufferSize],
+// PushBack returns [ErrBufferLimitExceeded] and
+// leaves the reader unchanged.
+func (r *Reader) PushBack(b []byte) error {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	if shift := len(b) - r.n; shift > 0 {
+		// make room at the front of the buffer
+		l := len(r.data)
+		if l+shift <= cap(r.data) {
+			r.data = r.data[:l+shift]
+			copy(r.data[shift:], r.data[:l])
+		} else {
+			if r.maxBuf > 0 && l+shift > r.maxBuf {
+				return ErrBufferLimitExceeded
+			}
+			old := r.data
+			r.data = make([]byte, l+shift)
+			copy(r.data[shift:], old)
 		}
-		r.data = r.data[0:0]
-		r.n = 0
+		r.n += shift
+		r.base -= int64(shift)
 	}
-	for r.state == nil {
-		// here we just do
-		// 1:1 reads and writes
+	r.n -= copy(r.data[r.n-len(b):r.n], b)
+	return nil
+}
+
+// PeekByte returns the next byte in the
+// stream without advancing the reader. It
+// returns [io.EOF] if the stream is empty.
+func (r *Reader) PeekByte() (byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	for r.buffered() < 1 && r.state == nil {
 		r.more()
-		if r.buffered() > 0 {
-			ii, err = w.Write(r.data)
-			i += int64(ii)
+	}
+	if r.buffered() < 1 {
+		return 0, r.err()
+	}
+	return r.data[r.n], nil
+}
+
+// ByteAt returns the byte 'off' bytes past the
+// current position in the stream without advancing
+// the reader, reading from the underlying reader and
+// growing the buffer as necessary. It returns the error
+// encountered (usually [io.EOF]) if the stream ends
+// first, and [bufio.ErrNegativeCount] if 'off' is negative.
+func (r *Reader) ByteAt(off int) (byte, error) {
+	if off < 0 {
+		return 0, bufio.ErrNegativeCount
+	}
+	b, err := r.Peek(off + 1)
+	if len(b) <= off {
+		return 0, err
+	}
+	return b[off], nil
+}
+
+// UnreadByte unreads the last byte read.
+// Only the last byte returned by ReadByte,
+// Read, ReadFull, or Next may be unread;
+// otherwise UnreadByte returns
+// [bufio.ErrInvalidUnreadByte].
+// UnreadByte implements [io.ByteScanner].
+func (r *Reader) UnreadByte() error {
+	if r.lastByte < 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	if r.n > 0 {
+		r.n--
+	} else {
+		// the byte was read directly from
+		// the underlying reader, so it has to
+		// be put back at the front of the buffer
+		r.data = append(r.data, 0)
+		copy(r.data[1:], r.data)
+		r.base--
+	}
+	r.data[r.n] = byte(r.lastByte)
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	return nil
+}
+
+// ReadRune reads a single UTF-8 encoded rune
+// and returns the rune and its size in bytes.
+// If the encoded rune is invalid, it consumes
+// one byte and returns [utf8.RuneError] with a size of 1.
+// ReadRune implements [io.RuneReader].
+func (r *Reader) ReadRune() (rune, int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	c, size := r.nextRune()
+	if size == 0 {
+		return 0, 0, r.err()
+	}
+	r.n += size
+	r.observe()
+	r.lastByte = int(r.data[r.n-1])
+	r.lastRuneSize = size
+	return c, size, nil
+}
+
+// UnreadRune unreads the last rune. If the
+// most recent method called on the reader was
+// not a [Reader.ReadRune], UnreadRune returns
+// [bufio.ErrInvalidUnreadRune].
+// UnreadRune implements [io.RuneScanner].
+func (r *Reader) UnreadRune() error {
+	if r.lastRuneSize < 0 {
+		return bufio.ErrInvalidUnreadRune
+	}
+	r.n -= r.lastRuneSize
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	return nil
+}
+
+// PeekRune is like [Reader.ReadRune],
+// but it does not advance the reader.
+func (r *Reader) PeekRune() (rune, int, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	c, size := r.nextRune()
+	if size == 0 {
+		return 0, 0, r.err()
+	}
+	return c, size, nil
+}
+
+// nextRune decodes the next buffered rune,
+// filling the buffer as necessary. It returns
+// a size of 0 if no bytes could be buffered.
+// An error that stops the fill is left pending
+// even if bytes are still buffered, so methods
+// that consume the buffer must drain it before
+// they report r.state.
+func (r *Reader) nextRune() (rune, int) {
+	// fill until we have a complete rune
+	// (or as much of one as we're going to get)
+	for r.buffered() < utf8.UTFMax && !utf8.FullRune(r.data[r.n:]) && r.state == nil {
+		r.more()
+	}
+	if r.buffered() < 1 {
+		return 0, 0
+	}
+	c := rune(r.data[r.n])
+	if c < utf8.RuneSelf {
+		return c, 1
+	}
+	return utf8.DecodeRune(r.data[r.n:])
+}
+
+// ReadBytes reads until the first occurrence
+// of 'delim' in the stream, returning a newly-allocated
+// slice containing the data up to and including
+// the delimiter. If ReadBytes encounters an error
+// before finding the delimiter, it returns the
+// data read before the error and the error itself
+// (often [io.EOF]).
+func (r *Reader) ReadBytes(delim byte) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	var out []byte
+	for {
+		if i := bytes.IndexByte(r.data[r.n:], delim); i >= 0 {
+			out = append(out, r.data[r.n:r.n+i+1]...)
+			r.n += i + 1
+			r.observe()
+			r.lastByte = int(delim)
+			return out, nil
+		}
+		// accumulate everything we have
+		// so far and keep looking
+		out = append(out, r.data[r.n:]...)
+		r.discard(r.buffered())
+		if r.state != nil {
+			return out, r.err()
+		}
+		r.more()
+	}
+}
+
+// ReadUntilAny is like [Reader.ReadBytes], but it
+// reads until the first occurrence of any of the
+// bytes in 'delims', and it also returns the delimiter
+// that was found. If the stream ends before any
+// delimiter is found, ReadUntilAny returns the bytes
+// read, a zero delimiter, and the error encountered
+// (usually [io.EOF]).
+func (r *Reader) ReadUntilAny(delims []byte) ([]byte, byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	var set [256]bool
+	for _, c := range delims {
+		set[c] = true
+	}
+	var out []byte
+	for {
+		for i, c := range r.data[r.n:] {
+			if set[c] {
+				out = append(out, r.data[r.n:r.n+i+1]...)
+				r.n += i + 1
+				r.observe()
+				r.lastByte = int(c)
+				return out, c, nil
+			}
+		}
+		out = append(out, r.data[r.n:]...)
+		r.discard(r.buffered())
+		if r.state != nil {
+			return out, 0, r.err()
+		}
+		r.more()
+	}
+}
+
+// ReadUntilFunc reads until 'fn' returns true and
+// returns the bytes read, including the byte for which
+// 'fn' returned true. 'fn' is called with each byte
+// and the byte before it (zero for the first byte),
+// so that it can recognize two-byte terminators like
+// "\r\n". The returned slice is newly allocated. If
+// the stream ends first, ReadUntilFunc returns the
+// bytes read and the error encountered (usually [io.EOF]).
+func (r *Reader) ReadUntilFunc(fn func(prev, cur byte) bool) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	var out []byte
+	var prev byte
+	for {
+		for i, c := range r.data[r.n:] {
+			if fn(prev, c) {
+				out = append(out, r.data[r.n:r.n+i+1]...)
+				r.n += i + 1
+				r.observe()
+				r.lastByte = int(c)
+				return out, nil
+			}
+			prev = c
+		}
+		out = append(out, r.data[r.n:]...)
+		r.discard(r.buffered())
+		if r.state != nil {
+			return out, r.err()
+		}
+		r.more()
+	}
+}
+
+// ReadString is like [Reader.ReadBytes],
+// but it returns a string.
+func (r *Reader) ReadString(delim byte) (string, error) {
+	b, err := r.ReadBytes(delim)
+	return string(b), err
+}
+
+// ReadSlice reads until the first occurrence of
+// 'delim' and returns a slice of the buffer up to
+// and including the delimiter. The returned slice
+// is only valid until the next reader method call.
+// Unlike [Reader.ReadBytes], ReadSlice never grows
+// the buffer: if the buffer fills before 'delim'
+// is found, the buffered bytes are consumed and
+// returned along with [ErrBufferFull]. If the stream
+// ends before 'delim' is found, the remaining bytes
+// are returned along with the error (usually [io.EOF]).
+func (r *Reader) ReadSlice(delim byte) ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	scanned := 0
+	for {
+		if i := bytes.IndexByte(r.data[r.n+scanned:], delim); i >= 0 {
+			end := r.n + scanned + i + 1
+			out := r.data[r.n:end]
+			r.n = end
+			r.observe()
+			r.lastByte = int(delim)
+			return out, nil
+		}
+		scanned = r.buffered()
+		if r.state != nil || len(r.data)-r.keep() == cap(r.data) {
+			out := r.data[r.n:]
+			r.n = len(r.data)
+			r.observe()
+			if r.state != nil {
+				return out, r.err()
+			}
+			return out, ErrBufferFull
+		}
+		r.more()
+	}
+}
+
+// ReadCString reads a NUL-terminated string and
+// returns it without the terminator. The reader
+// is advanced past the terminator. If the stream
+// ends before a NUL byte, the partial string is
+// returned along with [io.ErrUnexpectedEOF], or
+// [io.EOF] if no bytes were read at all. If a
+// limit has been set with [Reader.SetMaxTokenSize]
+// and no terminator is found within that many
+// bytes, those bytes are consumed and returned
+// along with [ErrTokenTooLong].
+func (r *Reader) ReadCString() (string, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	var out []byte
+	for {
+		buf := r.data[r.n:]
+		if r.maxToken > 0 && len(buf) > r.maxToken-len(out) {
+			buf = buf[:r.maxToken-len(out)+1]
+		}
+		if i := bytes.IndexByte(buf, 0); i >= 0 {
+			out = append(out, buf[:i]...)
+			r.n += i + 1
+			r.observe()
+			r.lastByte = 0
+			return string(out), nil
+		}
+		if r.maxToken > 0 && len(buf) > r.maxToken-len(out) {
+			buf = buf[:len(buf)-1]
+			out = append(out, buf...)
+			r.discard(len(buf))
+			return string(out), ErrTokenTooLong
+		}
+		out = append(out, buf...)
+		r.discard(len(buf))
+		if r.state != nil {
+			if len(out) == 0 {
+				return "", r.err()
+			}
+			return string(out), r.noEOF()
+		}
+		r.more()
+	}
+}
+
+// ReadLine reads a single line, stripping
+// the trailing "\r\n" or "\n". The returned
+// slice is newly allocated and may be retained.
+// Lines longer than the buffer are returned
+// whole. If the final line in the stream is not
+// terminated by a newline, it is returned with
+// a nil error, and [io.EOF] is returned by the
+// subsequent call.
+func (r *Reader) ReadLine() ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = nil
+		}
+		return line, err
+	}
+	line = line[:len(line)-1]
+	if l := len(line); l > 0 && line[l-1] == '\r' {
+		line = line[:l-1]
+	}
+	return line, nil
+}
+
+// Mark returns the current position in
+// the stream and pins the buffer so that
+// every byte read after the mark is retained
+// until the mark is released with [Reader.Unmark].
+// The reader may be moved back to a mark
+// with [Reader.Rewind].
+//
+// While any mark is outstanding, the buffer
+// grows instead of discarding pinned bytes,
+// reads are never made directly into
+// caller-supplied slices, and [Reader.Skip]
+// reads through the buffer rather than
+// using the underlying [io.Seeker], so
+// a Rewind never has to seek backwards.
+func (r *Reader) Mark() int {
+	m := r.base + int64(r.n)
+	r.marks = append(r.marks, m)
+	return int(m)
+}
+
+// Rewind moves the reader back (or forward)
+// to 'mark', which must be an outstanding
+// mark returned by [Reader.Mark]. The mark
+// remains outstanding. If 'mark' is not outstanding,
+// Rewind returns [ErrInvalidMark].
+func (r *Reader) Rewind(mark int) error {
+	for _, m := range r.marks {
+		if m == int64(mark) {
+			r.n = int(m - r.base)
+			r.lastByte = -1
+			r.lastRuneSize = -1
+			return nil
+		}
+	}
+	return ErrInvalidMark
+}
+
+// Unmark releases 'mark', allowing the
+// bytes it pinned to be discarded. Unmark
+// does not move the reader. If 'mark'
+// is not outstanding, Unmark returns
+// [ErrInvalidMark].
+func (r *Reader) Unmark(mark int) error {
+	for i, m := range r.marks {
+		if m == int64(mark) {
+			r.marks = append(r.marks[:i], r.marks[i+1:]...)
+			return nil
+		}
+	}
+	return ErrInvalidMark
+}
+
+// State is a saved position in the stream,
+// returned by [Reader.Snapshot].
+type State struct {
+	pos int64
+}
+
+// Snapshot saves the current position in the
+// stream so that it can later be returned to
+// with [Reader.Restore]. Like [Reader.Mark], a
+// snapshot pins every byte read after it in the
+// buffer, so the buffer grows for as long as the
+// snapshot is held; every snapshot must eventually
+// be passed to either [Reader.Restore] or
+// [Reader.DropSnapshot] to release those bytes.
+func (r *Reader) Snapshot() State {
+	pos := r.Offset()
+	r.marks = append(r.marks, pos)
+	return State{pos: pos}
+}
+
+// Restore moves the reader to the position saved
+// by 's' and releases the snapshot. Other snapshots
+// remain outstanding, whether they were taken before
+// or after 's'. If 's' has already been released (or
+// the reader has been reset since it was taken), its
+// bytes may have been discarded, and Restore returns
+// [ErrInvalidMark] without moving the reader.
+func (r *Reader) Restore(s State) error {
+	for i, m := range r.marks {
+		if m == s.pos {
+			r.n = int(m - r.base)
+			r.marks = append(r.marks[:i], r.marks[i+1:]...)
+			r.lastByte = -1
+			r.lastRuneSize = -1
+			return nil
+		}
+	}
+	return ErrInvalidMark
+}
+
+// DropSnapshot releases 's' without moving the
+// reader. If 's' has already been released, it
+// returns [ErrInvalidMark].
+func (r *Reader) DropSnapshot(s State) error {
+	return r.Unmark(int(s.pos))
+}
+
+// ReadAll reads from the reader until EOF and
+// returns a newly-allocated slice containing all
+// of the remaining bytes in the stream. A successful
+// call returns a nil error, not [io.EOF].
+func (r *Reader) ReadAll() ([]byte, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	out := make([]byte, 0, max(2*r.buffered(), minReaderSize))
+	for {
+		// append grows 'out' geometrically
+		out = append(out, r.data[r.n:]...)
+		r.discard(r.buffered())
+		if r.state != nil {
+			break
+		}
+		r.more()
+	}
+	if r.state != io.EOF {
+		return out, r.err()
+	}
+	r.err()
+	return out, nil
+}
+
+// ScanBytes repeatedly calls 'fn' with the buffered
+// bytes and advances the reader by the number of
+// bytes 'fn' reports as consumed. When 'fn' consumes
+// nothing, or returns [ErrNeedMore], more bytes are
+// read into the buffer (growing it if it is full)
+// before 'fn' is called again. ScanBytes stops and
+// returns the error when 'fn' returns any other
+// non-nil error. It returns nil when the stream
+// ends with every byte consumed, or
+// [io.ErrUnexpectedEOF] if 'fn' still needs more.
+// The slice passed to 'fn' is only valid for the
+// duration of the call.
+func (r *Reader) ScanBytes(fn func([]byte) (consumed int, err error)) error {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	for {
+		if r.buffered() == 0 {
+			if r.state != nil {
+				if err := r.err(); err != io.EOF {
+					return err
+				}
+				return nil
+			}
+			r.more()
+			continue
+		}
+		c, err := fn(r.data[r.n:])
+		if c < 0 {
+			return bufio.ErrNegativeAdvance
+		}
+		if c > r.buffered() {
+			return bufio.ErrAdvanceTooFar
+		}
+		r.discard(c)
+		if errors.Is(err, ErrNeedMore) || (err == nil && c == 0) {
+			if r.state != nil {
+				return r.noEOF()
+			}
+			r.more()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CopyN writes exactly 'n' bytes from the reader
+// to 'w', like [io.CopyN]: it returns the number of
+// bytes written and [io.EOF] if the stream ends
+// before 'n' bytes have been copied. Buffered bytes
+// are written first, and the rest are written
+// directly out of the read buffer, so no additional
+// buffer is allocated. The reader is advanced by
+// the number of bytes written.
+func (r *Reader) CopyN(w io.Writer, n int64) (int64, error) {
+	return r.writeTo(w, max64(n, 0))
+}
+
+// WriteTo implements [io.WriterTo]. If 'w' is an
+// [io.ReaderFrom] and the underlying reader is an
+// [io.Seeker] (as with an [*os.File]), the buffered
+// bytes are written first and the rest of the stream
+// is handed to w.ReadFrom, which may be able to copy
+// it without passing it through user space. In that
+// case w.ReadFrom decides how to handle empty reads,
+// and [Reader.SetMaxEmptyReads] does not apply.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	if rf, ok := w.(io.ReaderFrom); ok && r.rs != nil && r.bypass() && r.maxWrite == 0 {
+		return r.handOff(w, rf.ReadFrom)
+	}
+	return r.WriteToN(w, -1)
+}
+
+// Copy writes the rest of the stream to 'w', like
+// [Reader.WriteTo], but after writing the buffered
+// bytes it hands the underlying reader to [io.Copy],
+// which can take advantage of an [io.WriterTo] or
+// [io.ReaderFrom] implementation on either side
+// instead of passing every byte through the read
+// buffer. It returns the total number of bytes written.
+// As with [io.Copy], empty reads on the underlying
+// reader are not limited by [Reader.SetMaxEmptyReads].
+// While a mark, tee, hash, or position tracking is
+// active, the bytes have to pass through the buffer,
+// so Copy is equivalent to WriteTo.
+func (r *Reader) Copy(w io.Writer) (int64, error) {
+	if !r.bypass() || r.maxWrite != 0 {
+		return r.WriteTo(w)
+	}
+	return r.handOff(w, func(src io.Reader) (int64, error) {
+		return io.Copy(w, src)
+	})
+}
+
+// WriteToBuffered is like [Reader.WriteTo], but after
+// writing the buffered bytes it copies the rest of
+// the stream through a transfer buffer of 'bufSize'
+// bytes, reading from the underlying reader directly.
+// This lets a reader with a small buffer move a large
+// stream in large chunks. The transfer buffer is
+// allocated once per call. If 'bufSize' is not larger
+// than the reader's buffer, or while a mark, tee, hash,
+// or position tracking is active, WriteToBuffered is
+// equivalent to WriteTo.
+func (r *Reader) WriteToBuffered(w io.Writer, bufSize int) (int64, error) {
+	if bufSize <= cap(r.data) || !r.bypass() || r.maxWrite != 0 {
+		return r.WriteTo(w)
+	}
+	return r.handOff(w, func(src io.Reader) (int64, error) {
+		buf := make([]byte, bufSize)
+		var i int64
+		for empty := 0; ; {
+			n, err := src.Read(buf)
+			if n > 0 {
+				empty = 0
+				nw, werr := w.Write(buf[:n])
+				i += int64(nw)
+				if werr == nil && nw < n {
+					werr = io.ErrShortWrite
+				}
+				if werr != nil {
+					return i, werr
+				}
+			} else if err == nil {
+				if empty++; empty >= r.maxEmptyReads() {
+					return i, r.emptyReadError()
+				}
+			}
+			if err == io.EOF {
+				return i, nil
+			}
 			if err != nil {
 				return i, err
 			}
-			r.data = r.data[0:0]
-			r.n = 0
 		}
+	})
+}
+
+// handOff writes the buffered bytes to 'w' and
+// then uses copyRest to copy the rest of the stream
+// from the underlying reader
+func (r *Reader) handOff(w io.Writer, copyRest func(src io.Reader) (int64, error)) (int64, error) {
+	i, err := r.writeTo(w, int64(r.buffered()))
+	if err != nil || r.state != nil {
+		if err == nil {
+			err = r.err()
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		return i, err
 	}
-	if r.state != io.EOF {
-		return i, r.err()
+	var src io.Reader = r.r
+	if r.limit >= 0 {
+		src = io.LimitReader(r.r, r.limit)
+	}
+	nn, err := copyRest(src)
+	r.reads++
+	r.filled += nn
+	r.reset(int(nn))
+	if r.limit >= 0 {
+		r.limit -= nn
+	}
+	return i + nn, err
+}
+
+// WriteToN is like [Reader.WriteTo], but it writes
+// at most 'n' bytes. If 'n' is negative, there is no
+// limit. Unlike [Reader.CopyN], reaching the end of
+// the stream before 'n' bytes have been written is
+// not an error.
+func (r *Reader) WriteToN(w io.Writer, n int64) (int64, error) {
+	i, err := r.writeTo(w, n)
+	if err == io.EOF {
+		err = nil
+	}
+	return i, err
+}
+
+// SetWriteChunkSize sets the largest slice passed
+// to a single Write call by [Reader.WriteTo],
+// [Reader.WriteToN], and [Reader.CopyN]. If 'n' is
+// less than 1, slices are as large as the buffered
+// data.
+func (r *Reader) SetWriteChunkSize(n int) {
+	if n < 1 {
+		n = 0
+	}
+	r.maxWrite = n
+}
+
+// writeTo writes up to 'n' bytes (or all of
+// them if 'n' is negative) to 'w', returning
+// the read error if the stream ends first
+func (r *Reader) writeTo(w io.Writer, n int64) (int64, error) {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	var i int64
+	for n < 0 || i < n {
+		if r.buffered() == 0 {
+			if r.state != nil {
+				return i, r.err()
+			}
+			r.more()
+			continue
+		}
+		chunk := r.data[r.n:]
+		if n >= 0 && int64(len(chunk)) > n-i {
+			chunk = chunk[:n-i]
+		}
+		if r.maxWrite > 0 && len(chunk) > r.maxWrite {
+			chunk = chunk[:r.maxWrite]
+		}
+		ii, err := w.Write(chunk)
+		// only what was written
+		// has been consumed
+		r.discard(ii)
+		i += int64(ii)
+		if err == nil && ii < len(chunk) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return i, err
+		}
 	}
 	return i, nil
 }
 
+func max64(a int64, b int64) int64 {
+	if a < b {
+		return b
+	}
+	return a
+}
+
+func min(a int, b int) int {
+	if a > b {
+		return b
+	}
+	return a
+}
+
 func max(a int, b int) int {
 	if a < b {
 		return b
@@ -1,11 +1,22 @@
 package fwd
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"net"
+	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -23,10 +34,16 @@ func (p partialReader) Read(b []byte) (int, error) {
 
 func randomBts(sz int) []byte {
 	o := make([]byte, sz)
-	for i := 0; i < len(o); i += 8 {
+	i := 0
+	for ; i+8 <= len(o); i += 8 {
 		j := (*int64)(unsafe.Pointer(&o[i]))
 		*j = rand.Int63()
 	}
+	// the tail is too short for a whole
+	// word; fill it a byte at a time
+	for ; i < len(o); i++ {
+		o[i] = byte(rand.Int63())
+	}
 	return o
 }
 
@@ -240,15 +257,14 @@ func TestSkipSeek(t *testing.T) {
 	// now try to skip past the end
 	rd.Reset(bytes.NewReader(bts))
 
-	// because of how bytes.Reader
-	// implements Seek, this should
-	// return (2000, nil)
+	// bytes.Reader will happily seek past
+	// the end, but only 1024 bytes exist
 	n, err = rd.Skip(2000)
-	if err != nil {
-		t.Fatal(err)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
 	}
-	if n != 2000 {
-		t.Fatalf("should have returned %d bytes; returned %d", 2000, n)
+	if n != 1024 {
+		t.Fatalf("should have returned %d bytes; returned %d", 1024, n)
 	}
 
 	// the next call to Read()
@@ -450,3 +466,3448 @@ func TestReaderBufCreation(t *testing.T) {
 		}
 	}
 }
+
+func TestUnreadByte(t *testing.T) {
+	bts := randomBts(512)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+
+	// nothing has been read yet
+	if err := rd.UnreadByte(); err != bufio.ErrInvalidUnreadByte {
+		t.Fatalf("expected %q; got %v", bufio.ErrInvalidUnreadByte, err)
+	}
+
+	for i := 0; i < len(bts); i++ {
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rd.UnreadByte(); err != nil {
+			t.Fatalf("offset %d: %s", i, err)
+		}
+		// can only unread once
+		if err := rd.UnreadByte(); err != bufio.ErrInvalidUnreadByte {
+			t.Fatalf("offset %d: expected %q; got %v", i, bufio.ErrInvalidUnreadByte, err)
+		}
+		again, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != again || b != bts[i] {
+			t.Fatalf("offset %d: %d in; %d and %d out", i, bts[i], b, again)
+		}
+	}
+
+	// Peek invalidates the last byte
+	rd.Reset(bytes.NewReader(bts))
+	rd.ReadByte()
+	rd.Peek(1)
+	if err := rd.UnreadByte(); err != bufio.ErrInvalidUnreadByte {
+		t.Fatalf("expected %q after Peek(); got %v", bufio.ErrInvalidUnreadByte, err)
+	}
+
+	// a large Read bypasses the buffer
+	rd.Reset(bytes.NewReader(bts))
+	big := make([]byte, 128)
+	n, err := rd.Read(big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.UnreadByte(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[n-1] {
+		t.Fatalf("expected %d after unread; got %d", bts[n-1], b)
+	}
+}
+
+func TestMarkRewind(t *testing.T) {
+	bts := randomBts(4096)
+
+	// a seekable reader should still
+	// be able to rewind past a Skip
+	for _, src := range []io.Reader{
+		partialReader{bytes.NewReader(bts)},
+		bytes.NewReader(bts),
+	} {
+		rd := NewReaderSize(src, 64)
+		rd.Skip(10)
+
+		m := rd.Mark()
+		if m != 10 {
+			t.Fatalf("expected mark at %d; got %d", 10, m)
+		}
+
+		// read well past the size of the buffer
+		if _, err := rd.Skip(1000); err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, 500)
+		if _, err := rd.ReadFull(out); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, bts[1010:1510]) {
+			t.Fatal("bytes not equal")
+		}
+
+		if err := rd.Rewind(m); err != nil {
+			t.Fatal(err)
+		}
+		out = make([]byte, 1500)
+		if _, err := rd.ReadFull(out); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, bts[10:1510]) {
+			t.Fatal("bytes not equal after Rewind()")
+		}
+
+		if err := rd.Unmark(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := rd.Rewind(m); err != ErrInvalidMark {
+			t.Fatalf("expected %q; got %v", ErrInvalidMark, err)
+		}
+		if err := rd.Unmark(m); err != ErrInvalidMark {
+			t.Fatalf("expected %q; got %v", ErrInvalidMark, err)
+		}
+
+		// without marks, the buffer
+		// stops growing
+		rest, err := ioutil.ReadAll(rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rest, bts[1510:]) {
+			t.Fatal("bytes not equal after Unmark()")
+		}
+	}
+}
+
+func TestReadBytes(t *testing.T) {
+	var in bytes.Buffer
+	for i := 0; i < 100; i++ {
+		in.Write(bytes.Repeat([]byte{'a' + byte(i%26)}, i))
+		in.WriteByte('\n')
+	}
+	in.WriteString("tail")
+	bts := in.Bytes()
+
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+	for i := 0; i < 100; i++ {
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append(bytes.Repeat([]byte{'a' + byte(i%26)}, i), '\n')
+		if !bytes.Equal(line, want) {
+			t.Fatalf("line %d: expected %q; got %q", i, want, line)
+		}
+	}
+
+	// no delimiter before EOF
+	line, err := rd.ReadBytes('\n')
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if string(line) != "tail" {
+		t.Fatalf("expected %q; got %q", "tail", line)
+	}
+}
+
+func TestReadString(t *testing.T) {
+	rd := NewReaderSize(bytes.NewReader([]byte(",foo,bar")), 16)
+
+	// delimiter as the very first byte
+	s, err := rd.ReadString(',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "," {
+		t.Fatalf("expected %q; got %q", ",", s)
+	}
+	s, err = rd.ReadString(',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "foo," {
+		t.Fatalf("expected %q; got %q", "foo,", s)
+	}
+	s, err = rd.ReadString(',')
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if s != "bar" {
+		t.Fatalf("expected %q; got %q", "bar", s)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	long := string(bytes.Repeat([]byte{'x'}, 100))
+	in := "first\r\nsecond\n\n" + long + "\nlast"
+	rd := NewReaderSize(partialReader{bytes.NewReader([]byte(in))}, 16)
+
+	for _, want := range []string{"first", "second", "", long, "last"} {
+		line, err := rd.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != want {
+			t.Fatalf("expected %q; got %q", want, line)
+		}
+	}
+	line, err := rd.ReadLine()
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if len(line) != 0 {
+		t.Fatalf("expected no bytes at EOF; got %q", line)
+	}
+}
+
+func TestPeekUntil(t *testing.T) {
+	bts := append(bytes.Repeat([]byte{'a'}, 300), ';', 'b')
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+
+	// the delimiter is well past the
+	// end of the buffer, so it has to grow
+	peek, err := rd.PeekUntil(';')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(peek, bts[:301]) {
+		t.Fatalf("expected %d bytes; got %d", 301, len(peek))
+	}
+
+	// should not have advanced
+	again, err := rd.PeekUntil(';')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, bts[:301]) {
+		t.Fatal("PeekUntil() advanced the reader")
+	}
+
+	if _, err := rd.Skip(len(peek)); err != nil {
+		t.Fatal(err)
+	}
+	peek, err = rd.PeekUntil(';')
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if string(peek) != "b" {
+		t.Fatalf("expected %q; got %q", "b", peek)
+	}
+}
+
+func TestSkipUntil(t *testing.T) {
+	bts := append(bytes.Repeat([]byte{'a'}, 300), ';', 'b', 'c')
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+
+	n, err := rd.SkipUntil(';')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 301 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 301, n)
+	}
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != 'b' {
+		t.Fatalf("expected %q; got %q", 'b', b)
+	}
+
+	n, err = rd.SkipUntil(';')
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+	if n != 1 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 1, n)
+	}
+}
+
+func TestReadRune(t *testing.T) {
+	str := "héllo, 世界! 🙂"
+	bts := []byte(str)
+	bts = append(bts, 0xff)       // invalid
+	bts = append(bts, "界"[:2]...) // truncated at EOF
+
+	// a tiny buffer guarantees that multibyte
+	// runes straddle buffer refills
+	for _, size := range []int{16, 17, 18, 19} {
+		rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, size)
+		rd.Peek(size - 1)
+
+		var out []rune
+		for {
+			c, n, err := rd.ReadRune()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != utf8.RuneLen(c) && !(c == utf8.RuneError && n == 1) {
+				t.Fatalf("rune %q has size %d", c, n)
+			}
+			out = append(out, c)
+		}
+		want := append([]rune(str), utf8.RuneError, utf8.RuneError, utf8.RuneError)
+		if string(out) != string(want) {
+			t.Fatalf("expected %q; got %q", string(want), string(out))
+		}
+	}
+}
+
+func TestPeekRune(t *testing.T) {
+	str := "a世\xffb"
+	rd := NewReaderSize(bytes.NewReader([]byte(str)), 16)
+
+	for _, want := range []struct {
+		c    rune
+		size int
+	}{{'a', 1}, {'世', 3}, {utf8.RuneError, 1}, {'b', 1}} {
+		// consecutive peeks should not advance
+		for i := 0; i < 2; i++ {
+			c, size, err := rd.PeekRune()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c != want.c || size != want.size {
+				t.Fatalf("expected %q (size %d); got %q (size %d)", want.c, want.size, c, size)
+			}
+		}
+		c, size, err := rd.ReadRune()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != want.c || size != want.size {
+			t.Fatalf("expected %q (size %d) from ReadRune(); got %q (size %d)", want.c, want.size, c, size)
+		}
+	}
+	if _, _, err := rd.PeekRune(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+}
+
+// PeekRune on a truncated final rune leaves io.EOF
+// pending; the bytes after it must still be readable
+func TestPeekRuneTruncated(t *testing.T) {
+	const str = "x\xe2\x82"
+	for _, tc := range []struct {
+		name string
+		read func(rd *Reader) ([]byte, error)
+	}{
+		{"ReadFull", func(rd *Reader) ([]byte, error) {
+			out := make([]byte, 8)
+			n, err := rd.ReadFull(out)
+			return out[:n], err
+		}},
+		{"ReadAtLeast", func(rd *Reader) ([]byte, error) {
+			out := make([]byte, 8)
+			n, err := rd.ReadAtLeast(out, 8)
+			return out[:n], err
+		}},
+		{"Read", func(rd *Reader) ([]byte, error) {
+			out := make([]byte, 8)
+			n, err := rd.Read(out)
+			return out[:n], err
+		}},
+		{"ReadAll", (*Reader).ReadAll},
+		{"WriteTo", func(rd *Reader) ([]byte, error) {
+			var buf bytes.Buffer
+			_, err := rd.WriteTo(&buf)
+			return buf.Bytes(), err
+		}},
+		{"ReadBytes", func(rd *Reader) ([]byte, error) {
+			return rd.ReadBytes('\n')
+		}},
+	} {
+		rd := NewReaderSize(bytes.NewReader([]byte(str)), 16)
+		if c, _, err := rd.ReadRune(); err != nil || c != 'x' {
+			t.Fatalf("expected 'x' from ReadRune(); got %q, %v", c, err)
+		}
+		if c, size, err := rd.PeekRune(); err != nil || c != utf8.RuneError || size != 1 {
+			t.Fatalf("expected a 1-byte utf8.RuneError from PeekRune(); got %q (size %d), %v", c, size, err)
+		}
+		out, _ := tc.read(rd)
+		if string(out) != str[1:] {
+			t.Fatalf("%s: expected %q; got %q", tc.name, str[1:], out)
+		}
+		if rd.Buffered() != 0 {
+			t.Fatalf("%s: expected nothing buffered; got %d bytes", tc.name, rd.Buffered())
+		}
+	}
+}
+
+func TestUnreadRune(t *testing.T) {
+	str := "a世界b"
+	rd := NewReaderSize(partialReader{bytes.NewReader([]byte(str))}, 16)
+
+	if err := rd.UnreadRune(); err != bufio.ErrInvalidUnreadRune {
+		t.Fatalf("expected %q; got %v", bufio.ErrInvalidUnreadRune, err)
+	}
+	for _, want := range str {
+		c, _, err := rd.ReadRune()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rd.UnreadRune(); err != nil {
+			t.Fatal(err)
+		}
+		if err := rd.UnreadRune(); err != bufio.ErrInvalidUnreadRune {
+			t.Fatalf("expected %q after a second UnreadRune(); got %v", bufio.ErrInvalidUnreadRune, err)
+		}
+		again, _, err := rd.ReadRune()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != want || again != want {
+			t.Fatalf("expected %q; got %q and %q", want, c, again)
+		}
+	}
+
+	// intervening calls invalidate the rune
+	rd.Reset(bytes.NewReader([]byte(str)))
+	rd.ReadRune()
+	rd.ReadByte()
+	if err := rd.UnreadRune(); err != bufio.ErrInvalidUnreadRune {
+		t.Fatalf("expected %q after ReadByte(); got %v", bufio.ErrInvalidUnreadRune, err)
+	}
+
+	// so does moving the buffer
+	rd.Reset(strings.NewReader("€uro"))
+	rd.ReadRune()
+	rd.Compact()
+	if err := rd.UnreadRune(); err != bufio.ErrInvalidUnreadRune {
+		t.Fatalf("expected %q after Compact(); got %v", bufio.ErrInvalidUnreadRune, err)
+	}
+	if c, _, err := rd.ReadRune(); c != 'u' || err != nil {
+		t.Fatalf("expected ('u', <nil>); got (%q, %v)", c, err)
+	}
+
+	rd.Reset(strings.NewReader("€uro"))
+	rd.ReadRune()
+	rd.Grow(100)
+	if err := rd.UnreadRune(); err != bufio.ErrInvalidUnreadRune {
+		t.Fatalf("expected %q after Grow(); got %v", bufio.ErrInvalidUnreadRune, err)
+	}
+	if p, err := rd.Peek(3); string(p) != "uro" || err != nil {
+		t.Fatalf("expected (%q, <nil>); got (%q, %v)", "uro", p, err)
+	}
+
+	rd = NewReaderSize(strings.NewReader("€uro"), 64)
+	rd.ReadRune()
+	rd.ShrinkTo(16)
+	if err := rd.UnreadRune(); err != bufio.ErrInvalidUnreadRune {
+		t.Fatalf("expected %q after ShrinkTo(); got %v", bufio.ErrInvalidUnreadRune, err)
+	}
+	if c, _, err := rd.ReadRune(); c != 'u' || err != nil {
+		t.Fatalf("expected ('u', <nil>); got (%q, %v)", c, err)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	bts := randomBts(4096)
+	for _, src := range []io.Reader{
+		partialReader{bytes.NewReader(bts)},
+		bytes.NewReader(bts),
+	} {
+		rd := NewReaderSize(src, 64)
+		var want int64
+		check := func(op string) {
+			t.Helper()
+			if rd.Offset() != want {
+				t.Fatalf("after %s: expected Offset() to be %d; got %d", op, want, rd.Offset())
+			}
+		}
+		check("NewReader")
+
+		rd.Peek(10)
+		check("Peek")
+		rd.ReadByte()
+		want++
+		check("ReadByte")
+		rd.Next(100)
+		want += 100
+		check("Next")
+		n, _ := rd.Read(make([]byte, 10))
+		want += int64(n)
+		check("Read")
+		rd.Skip(1000)
+		want += 1000
+		check("Skip")
+		rd.ReadFull(make([]byte, 200))
+		want += 200
+		check("ReadFull")
+		rd.UnreadByte()
+		want--
+		check("UnreadByte")
+		n, _ = rd.Read(make([]byte, 100))
+		want += int64(n)
+		check("Read")
+		rd.WriteTo(ioutil.Discard)
+		want = int64(len(bts))
+		check("WriteTo")
+
+		rd.Reset(bytes.NewReader(bts))
+		want = 0
+		check("Reset")
+	}
+}
+
+func TestSkipBack(t *testing.T) {
+	bts := randomBts(1024)
+
+	// within the buffer
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	if _, err := rd.Next(10); err != nil {
+		t.Fatal(err)
+	}
+	n, err := rd.Skip(-4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != -4 {
+		t.Fatalf("expected Skip(-4) to return %d; got %d", -4, n)
+	}
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[6] {
+		t.Fatalf("expected %d; got %d", bts[6], b)
+	}
+
+	// past the start of the buffer
+	// without a seeker
+	rd.Skip(500)
+	if _, err := rd.Skip(-400); err != ErrNoRewind {
+		t.Fatalf("expected %q; got %v", ErrNoRewind, err)
+	}
+
+	// ... and with one
+	rd = NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Skip(500)
+	rd.Peek(10)
+	n, err = rd.Skip(-400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != -400 {
+		t.Fatalf("expected Skip(-400) to return %d; got %d", -400, n)
+	}
+	if rd.Offset() != 100 {
+		t.Fatalf("expected Offset() to be %d; got %d", 100, rd.Offset())
+	}
+	b, err = rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[100] {
+		t.Fatalf("expected %d; got %d", bts[100], b)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+
+	var _ io.Seeker = rd
+
+	check := func(pos int64) {
+		t.Helper()
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[pos] {
+			t.Fatalf("at %d: expected %d; got %d", pos, bts[pos], b)
+		}
+		rd.UnreadByte()
+	}
+
+	pos, err := rd.Seek(100, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 100 {
+		t.Fatalf("expected position %d; got %d", 100, pos)
+	}
+	check(100)
+
+	pos, err = rd.Seek(-50, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 50 {
+		t.Fatalf("expected position %d; got %d", 50, pos)
+	}
+	check(50)
+
+	pos, err = rd.Seek(700, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 700 {
+		t.Fatalf("expected position %d; got %d", 700, pos)
+	}
+	check(700)
+
+	pos, err = rd.Seek(-24, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 1000 || rd.Offset() != 1000 {
+		t.Fatalf("expected position %d; got %d (Offset() is %d)", 1000, pos, rd.Offset())
+	}
+	check(1000)
+
+	// not seekable
+	rd = NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	pos, err = rd.Seek(100, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 100 {
+		t.Fatalf("expected position %d; got %d", 100, pos)
+	}
+	check(100)
+	if _, err := rd.Seek(0, io.SeekStart); err != ErrNotSeeker {
+		t.Fatalf("expected %q; got %v", ErrNotSeeker, err)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	bts := randomBts(1024)
+	for _, src := range []io.Reader{
+		partialReader{bytes.NewReader(bts)},
+		bytes.NewReader(bts),
+	} {
+		rd := NewReaderSize(src, 64)
+		n, err := rd.Discard(500)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 500 {
+			t.Fatalf("expected to discard %d bytes; discarded %d", 500, n)
+		}
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[500] {
+			t.Fatalf("expected %d; got %d", bts[500], b)
+		}
+		if _, err := rd.Discard(-1); err != bufio.ErrNegativeCount {
+			t.Fatalf("expected %q; got %v", bufio.ErrNegativeCount, err)
+		}
+	}
+
+	// running out early is a plain EOF
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	n, err := rd.Discard(2000)
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if n != len(bts) {
+		t.Fatalf("expected to discard %d bytes; discarded %d", len(bts), n)
+	}
+}
+
+func TestFill(t *testing.T) {
+	bts := randomBts(100)
+	c := readCounter{r: bytes.NewReader(bts)}
+	rd := NewReaderSize(&c, 64)
+
+	if err := rd.Fill(); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Buffered() != 64 {
+		t.Fatalf("expected %d buffered bytes; got %d", 64, rd.Buffered())
+	}
+
+	// a full buffer shouldn't read
+	if err := rd.Fill(); err != nil {
+		t.Fatal(err)
+	}
+	if c.count != 1 {
+		t.Fatalf("expected %d reads; got %d", 1, c.count)
+	}
+
+	rd.Skip(10)
+	if err := rd.Fill(); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Buffered() != 64 {
+		t.Fatalf("expected %d buffered bytes after a second Fill(); got %d", 64, rd.Buffered())
+	}
+
+	rd.Skip(rd.Buffered())
+	if err := rd.Fill(); err != nil {
+		t.Fatal(err)
+	}
+	rd.Skip(rd.Buffered())
+	if err := rd.Fill(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if rd.Offset() != 100 {
+		t.Fatalf("expected to have consumed %d bytes; consumed %d", 100, rd.Offset())
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	bts := randomBts(5000)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.Peek(10)
+	rd.Skip(3)
+
+	out, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[3:]) {
+		t.Fatalf("bytes not equal; %d bytes in and %d bytes out", len(bts)-3, len(out))
+	}
+
+	// nothing left
+	out, err = rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("expected an empty slice; got %d bytes", len(out))
+	}
+}
+
+func TestIndexByte(t *testing.T) {
+	bts := append(bytes.Repeat([]byte{'a'}, 200), 'b', 'a')
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 32)
+	rd.Skip(10)
+
+	i, err := rd.IndexByte('b')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != 190 {
+		t.Fatalf("expected offset %d; got %d", 190, i)
+	}
+	if rd.Offset() != 10 {
+		t.Fatalf("IndexByte() advanced the reader to %d", rd.Offset())
+	}
+
+	i, err = rd.IndexByte('c')
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if i != -1 {
+		t.Fatalf("expected offset %d; got %d", -1, i)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	bts := randomBts(300)
+	buf := make([]byte, 0, 128)
+	rd := NewReaderBuf(bytes.NewReader(bts), buf)
+	rd.Peek(10)
+
+	out := rd.Release()
+	if cap(out) != 128 || len(out) != 0 {
+		t.Fatalf("expected an empty buffer with capacity %d; got len %d, cap %d", 128, len(out), cap(out))
+	}
+	if &out[:1][0] != &buf[:1][0] {
+		t.Fatal("Release() did not return the original buffer")
+	}
+	if rd.BufferSize() != 0 {
+		t.Fatalf("expected BufferSize() to be 0 after Release(); got %d", rd.BufferSize())
+	}
+
+	rd.ResetBuf(bytes.NewReader(bts), make([]byte, 0, 64))
+	if rd.BufferSize() != 64 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 64, rd.BufferSize())
+	}
+	all, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, bts) {
+		t.Fatal("bytes not equal after ResetBuf()")
+	}
+}
+
+func TestGrow(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Peek(64)
+	rd.Skip(14)
+
+	rd.Grow(10)
+	if rd.BufferSize() != 64 {
+		t.Fatalf("expected Grow(10) to be a no-op; BufferSize() is %d", rd.BufferSize())
+	}
+
+	rd.Grow(500)
+	if want := 500 + 50; rd.BufferSize() < want {
+		t.Fatalf("expected BufferSize() >= %d; got %d", want, rd.BufferSize())
+	}
+	if rd.Buffered() != 50 {
+		t.Fatalf("expected Grow() to retain %d buffered bytes; got %d", 50, rd.Buffered())
+	}
+
+	// should not need to reallocate
+	size := rd.BufferSize()
+	peek, err := rd.Peek(550)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd.BufferSize() != size {
+		t.Fatalf("Peek() reallocated after Grow(); BufferSize() is %d, was %d", rd.BufferSize(), size)
+	}
+	if !bytes.Equal(peek, bts[14:564]) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestPeekByte(t *testing.T) {
+	bts := randomBts(100)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+
+	for i := range bts {
+		b, err := rd.PeekByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[i] || again != bts[i] {
+			t.Fatalf("offset %d: %d in; %d peeked and %d read", i, bts[i], b, again)
+		}
+	}
+	if _, err := rd.PeekByte(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	bts := randomBts(1024)
+	c := readCounter{r: bytes.NewReader(bts)}
+	rd := NewReaderSize(&c, 64)
+	rd.Peek(64)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// already buffered; no need to check
+	peek, err := rd.PeekContext(ctx, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(peek, bts[:32]) {
+		t.Fatal("bytes not equal")
+	}
+
+	peek, err = rd.PeekContext(ctx, 128)
+	if err != context.Canceled {
+		t.Fatalf("expected %q; got %v", context.Canceled, err)
+	}
+	if len(peek) != 64 {
+		t.Fatalf("expected %d bytes; got %d", 64, len(peek))
+	}
+
+	out := make([]byte, 100)
+	n, err := rd.ReadFullContext(ctx, out)
+	if err != context.Canceled {
+		t.Fatalf("expected %q; got %v", context.Canceled, err)
+	}
+	if n != 64 || !bytes.Equal(out[:n], bts[:64]) {
+		t.Fatalf("expected the %d buffered bytes; got %d", 64, n)
+	}
+	if c.count != 1 {
+		t.Fatalf("expected %d reads; got %d", 1, c.count)
+	}
+
+	// the reader is still usable
+	n, err = rd.ReadFullContext(context.Background(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[64:164]) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestLimit(t *testing.T) {
+	bts := randomBts(1024)
+	for _, src := range []io.Reader{
+		partialReader{bytes.NewReader(bts)},
+		bytes.NewReader(bts),
+	} {
+		rd := NewReaderSize(src, 64)
+		rd.Limit(500)
+
+		out := make([]byte, 300)
+		if _, err := rd.ReadFull(out); err != nil {
+			t.Fatal(err)
+		}
+		n, err := rd.Skip(1000)
+		if err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+		}
+		if n != 200 {
+			t.Fatalf("expected to skip %d bytes; skipped %d", 200, n)
+		}
+		if _, err := rd.ReadByte(); err != io.EOF {
+			t.Fatalf("expected %q; got %v", io.EOF, err)
+		}
+
+		// lifting the limit
+		rd.Limit(-1)
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[500] {
+			t.Fatalf("expected %d; got %d", bts[500], b)
+		}
+	}
+
+	// Reset clears the limit
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Limit(10)
+	rd.Reset(bytes.NewReader(bts))
+	all, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(bts) {
+		t.Fatalf("expected %d bytes after Reset(); got %d", len(bts), len(all))
+	}
+}
+
+// errReader returns its bytes
+// and then a non-EOF error
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err == io.EOF {
+		err = e.err
+	}
+	return n, err
+}
+
+func TestErr(t *testing.T) {
+	bts := randomBts(100)
+	boom := errors.New("boom")
+
+	// the error arrives along with the data
+	src := &errReader{r: iotest.DataErrReader(bytes.NewReader(bts)), err: boom}
+	rd := NewReaderSize(src, 16)
+
+	if rd.Err() != nil {
+		t.Fatalf("expected no error; got %v", rd.Err())
+	}
+	out := make([]byte, 200)
+	n, err := rd.Read(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(bts) {
+		t.Fatalf("expected to read %d bytes; read %d", len(bts), n)
+	}
+
+	// Err should not clear the error
+	for i := 0; i < 2; i++ {
+		if rd.Err() != boom {
+			t.Fatalf("expected %q; got %v", boom, rd.Err())
+		}
+	}
+	rd.ClearErr()
+	if rd.Err() != nil {
+		t.Fatalf("expected no error after ClearErr(); got %v", rd.Err())
+	}
+
+	// the underlying reader is tried again
+	if _, err := rd.Read(out); err != boom {
+		t.Fatalf("expected %q; got %v", boom, err)
+	}
+}
+
+func TestReadErrorTail(t *testing.T) {
+	bts := randomBts(40)
+	boom := errors.New("boom")
+
+	// the source hands over all of its
+	// data and the error in a single call
+	src := &errReader{r: iotest.DataErrReader(bytes.NewReader(bts)), err: boom}
+	rd := NewReaderSize(src, 64)
+
+	if n, err := rd.Skip(10); err != nil || n != 10 {
+		t.Fatalf("expected (10, <nil>) from Skip(); got (%d, %v)", n, err)
+	}
+	var out []byte
+	p := make([]byte, 7)
+	for {
+		n, err := rd.Read(p)
+		out = append(out, p[:n]...)
+		if err != nil {
+			if n != 0 {
+				t.Fatalf("expected no bytes along with the error; got %d", n)
+			}
+			if err != boom {
+				t.Fatalf("expected %q; got %v", boom, err)
+			}
+			break
+		}
+		if n == 0 {
+			t.Fatal("expected Read() to make progress")
+		}
+	}
+	if !bytes.Equal(out, bts[10:]) {
+		t.Fatalf("expected %d remaining bytes; got %d", len(bts)-10, len(out))
+	}
+}
+
+// stepReader returns each of its steps
+// from one call to Read, and then io.EOF
+type stepReader []step
+
+type step struct {
+	data string
+	err  error
+}
+
+func (s *stepReader) Read(p []byte) (int, error) {
+	if len(*s) == 0 {
+		return 0, io.EOF
+	}
+	st := (*s)[0]
+	*s = (*s)[1:]
+	return copy(p, st.data), st.err
+}
+
+func TestReadErrorOnce(t *testing.T) {
+	boom := errors.New("boom")
+	src := &stepReader{{"hello", boom}, {"abc", nil}}
+	rd := NewReaderSize(src, 16)
+
+	p := make([]byte, 32)
+	for i, want := range []struct {
+		data string
+		err  error
+	}{
+		{"hello", nil},
+		{"", boom},
+		{"abc", nil},
+		{"", io.EOF},
+	} {
+		n, err := rd.Read(p)
+		if string(p[:n]) != want.data || err != want.err {
+			t.Fatalf("call %d: expected (%q, %v) from Read(); got (%q, %v)", i, want.data, want.err, p[:n], err)
+		}
+	}
+}
+
+func TestSetPromoteEOF(t *testing.T) {
+	bts := randomBts(100)
+	rd := NewReaderSize(bytes.NewReader(bts), 16)
+	rd.SetPromoteEOF(false)
+
+	if _, err := rd.Next(200); err != io.EOF {
+		t.Fatalf("expected %q from Next(); got %v", io.EOF, err)
+	}
+	rd.ResetKeepOptions(bytes.NewReader(bts))
+	if _, err := rd.ReadFull(make([]byte, 200)); err != io.EOF {
+		t.Fatalf("expected %q from ReadFull(); got %v", io.EOF, err)
+	}
+	rd.ResetKeepOptions(partialReader{bytes.NewReader(bts)})
+	if _, err := rd.Skip(200); err != io.EOF {
+		t.Fatalf("expected %q from Skip(); got %v", io.EOF, err)
+	}
+
+	rd.SetPromoteEOF(true)
+	rd.Reset(bytes.NewReader(bts))
+	if _, err := rd.Next(200); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q from Next(); got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestTee(t *testing.T) {
+	bts := randomBts(4096)
+	for _, src := range []io.Reader{
+		partialReader{bytes.NewReader(bts)},
+		bytes.NewReader(bts),
+	} {
+		rd := NewReaderSize(src, 64)
+		rd.Skip(10)
+
+		var tee bytes.Buffer
+		rd.Tee(&tee)
+
+		rd.Peek(20) // not consumed
+		rd.ReadByte()
+		rd.UnreadByte()
+		rd.ReadByte() // only teed once
+		rd.Next(100)
+		rd.Skip(1000) // skipped bytes are teed
+		rd.ReadFull(make([]byte, 500))
+		rd.Read(make([]byte, 200))
+		rd.ReadRune()
+		rd.SkipUntil(bts[2000])
+
+		want := bts[10:rd.Offset()]
+		if !bytes.Equal(tee.Bytes(), want) {
+			t.Fatalf("expected %d teed bytes; got %d", len(want), tee.Len())
+		}
+
+		rd.Tee(nil)
+		rd.WriteTo(ioutil.Discard)
+		if tee.Len() != len(want) {
+			t.Fatalf("wrote %d bytes after Tee(nil)", tee.Len()-len(want))
+		}
+	}
+
+	// write errors are surfaced
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	boom := errors.New("boom")
+	rd.Tee(&errWriter{err: boom})
+	rd.Next(100)
+	if _, err := rd.Next(100); err != boom {
+		t.Fatalf("expected %q; got %v", boom, err)
+	}
+}
+
+type errWriter struct {
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) { return 0, e.err }
+
+func TestPeekAll(t *testing.T) {
+	bts := randomBts(100)
+	c := readCounter{r: bytes.NewReader(bts)}
+	rd := NewReaderSize(&c, 64)
+
+	if len(rd.PeekAll()) != 0 {
+		t.Fatalf("expected no buffered bytes; got %d", len(rd.PeekAll()))
+	}
+	if c.count != 0 {
+		t.Fatalf("PeekAll() read from the underlying reader")
+	}
+	rd.Peek(10)
+	rd.Skip(4)
+	all := rd.PeekAll()
+	if len(all) != rd.Buffered() || !bytes.Equal(all, bts[4:4+len(all)]) {
+		t.Fatal("PeekAll() did not return the buffered bytes")
+	}
+	if c.count != 1 {
+		t.Fatalf("expected %d reads; got %d", 1, c.count)
+	}
+}
+
+func TestScanBytes(t *testing.T) {
+	// length-prefixed records
+	var in []byte
+	for i := 0; i < 200; i++ {
+		in = append(in, byte(i))
+		in = append(in, bytes.Repeat([]byte{byte(i)}, i)...)
+	}
+	rd := NewReaderSize(partialReader{bytes.NewReader(in)}, 16)
+
+	var records [][]byte
+	err := rd.ScanBytes(func(b []byte) (int, error) {
+		if len(b) < 1 || len(b) < 1+int(b[0]) {
+			return 0, ErrNeedMore
+		}
+		l := int(b[0])
+		records = append(records, append([]byte(nil), b[1:1+l]...))
+		return 1 + l, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 200 {
+		t.Fatalf("expected %d records; got %d", 200, len(records))
+	}
+	for i, rec := range records {
+		if !bytes.Equal(rec, bytes.Repeat([]byte{byte(i)}, i)) {
+			t.Fatalf("record %d: got %d bytes", i, len(rec))
+		}
+	}
+
+	// truncated record
+	rd = NewReaderSize(bytes.NewReader([]byte{5, 1, 2}), 16)
+	err = rd.ScanBytes(func(b []byte) (int, error) {
+		if len(b) < 1+int(b[0]) {
+			return 0, nil
+		}
+		return 1 + int(b[0]), nil
+	})
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// callback errors are returned
+	boom := errors.New("boom")
+	rd = NewReaderSize(bytes.NewReader(in), 16)
+	err = rd.ScanBytes(func(b []byte) (int, error) {
+		return 1, boom
+	})
+	if err != boom {
+		t.Fatalf("expected %q; got %v", boom, err)
+	}
+	if rd.Offset() != 1 {
+		t.Fatalf("expected the reader to advance %d byte; advanced %d", 1, rd.Offset())
+	}
+}
+
+func TestResetSize(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Peek(10)
+
+	rd.ResetSize(bytes.NewReader(bts), 512)
+	if rd.BufferSize() != 512 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 512, rd.BufferSize())
+	}
+	if rd.Buffered() != 0 {
+		t.Fatalf("expected 0 buffered bytes; got %d", rd.Buffered())
+	}
+
+	// smaller sizes keep the existing buffer
+	rd.ResetSize(bytes.NewReader(bts), 32)
+	if rd.BufferSize() != 512 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 512, rd.BufferSize())
+	}
+	all, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, bts) {
+		t.Fatal("bytes not equal after ResetSize()")
+	}
+
+	var zero Reader
+	zero.Reset(bytes.NewReader(bts))
+	if zero.BufferSize() != minReaderSize {
+		t.Fatalf("expected BufferSize() to be %d; got %d", minReaderSize, zero.BufferSize())
+	}
+}
+
+func TestPosition(t *testing.T) {
+	in := "first line\nsecond\n\nfourth line is longer than the buffer\nlast"
+	for _, src := range []func() io.Reader{
+		func() io.Reader { return partialReader{bytes.NewReader([]byte(in))} },
+		func() io.Reader { return bytes.NewReader([]byte(in)) },
+	} {
+		rd := NewReaderSize(src(), 16)
+		rd.EnablePositionTracking()
+
+		check := func(line, col int) {
+			t.Helper()
+			l, c := rd.Position()
+			if l != line || c != col {
+				t.Fatalf("expected line %d, col %d; got line %d, col %d", line, col, l, c)
+			}
+		}
+		check(1, 1)
+		rd.Next(5)
+		check(1, 6)
+		rd.Peek(10)
+		check(1, 6)
+		rd.ReadLine()
+		check(2, 1)
+		rd.Skip(9)
+		check(4, 2)
+		rd.ReadRune()
+		rd.UnreadRune()
+		rd.ReadRune()
+		check(4, 3)
+		rd.WriteTo(ioutil.Discard)
+		check(5, 5)
+
+		rd.ResetKeepOptions(src())
+		check(1, 1)
+		rd.SkipUntil('\n')
+		check(2, 1)
+	}
+}
+
+func TestSkipWhile(t *testing.T) {
+	space := func(c byte) bool { return c == ' ' || c == '\t' }
+	in := "x" + string(bytes.Repeat([]byte{' '}, 100)) + "\ty  "
+	rd := NewReaderSize(partialReader{bytes.NewReader([]byte(in))}, 16)
+
+	n, err := rd.SkipWhile(space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 0, n)
+	}
+	rd.ReadByte()
+	n, err = rd.SkipWhile(space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 101 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 101, n)
+	}
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != 'y' {
+		t.Fatalf("expected %q; got %q", 'y', b)
+	}
+	n, err = rd.SkipWhile(space)
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if n != 2 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 2, n)
+	}
+}
+
+func TestPeekWhile(t *testing.T) {
+	alpha := func(c byte) bool { return c >= 'a' && c <= 'z' }
+	ident := bytes.Repeat([]byte("abcdefghij"), 10)
+	in := append(append([]byte(nil), ident...), "(x)"...)
+	rd := NewReaderSize(partialReader{bytes.NewReader(in)}, 16)
+
+	// the run is longer than the buffer
+	peek, err := rd.PeekWhile(alpha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(peek, ident) {
+		t.Fatalf("expected %q; got %q", ident, peek)
+	}
+	if rd.Offset() != 0 {
+		t.Fatal("PeekWhile() advanced the reader")
+	}
+	rd.Skip(len(peek))
+
+	peek, err = rd.PeekWhile(alpha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peek) != 0 {
+		t.Fatalf("expected an empty run; got %q", peek)
+	}
+	rd.Skip(1)
+	peek, err = rd.PeekWhile(func(c byte) bool { return c != '!' })
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if string(peek) != "x)" {
+		t.Fatalf("expected %q; got %q", "x)", peek)
+	}
+}
+
+func TestReadAtLeast(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.Peek(10)
+
+	out := make([]byte, 300)
+	if _, err := rd.ReadAtLeast(out, 301); err != io.ErrShortBuffer {
+		t.Fatalf("expected %q; got %v", io.ErrShortBuffer, err)
+	}
+
+	var all []byte
+	for len(all) < 1000 {
+		n, err := rd.ReadAtLeast(out[:200], 200)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n < 200 {
+			t.Fatalf("read only %d bytes", n)
+		}
+		all = append(all, out[:n]...)
+	}
+	if !bytes.Equal(all, bts[:len(all)]) {
+		t.Fatal("bytes not equal")
+	}
+
+	n, err := rd.ReadAtLeast(out, 200)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+	if n != 1024-len(all) {
+		t.Fatalf("expected to read %d bytes; read %d", 1024-len(all), n)
+	}
+	n, err = rd.ReadAtLeast(out, 1)
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if n != 0 {
+		t.Fatalf("expected to read 0 bytes; read %d", n)
+	}
+}
+
+// emptyReader returns (0, nil) 'empty' times
+// before each successful read
+type emptyReader struct {
+	r     io.Reader
+	empty int
+	count int
+}
+
+func (e *emptyReader) Read(p []byte) (int, error) {
+	if e.count < e.empty {
+		e.count++
+		return 0, nil
+	}
+	e.count = 0
+	return e.r.Read(p)
+}
+
+func TestMaxEmptyReads(t *testing.T) {
+	bts := randomBts(100)
+
+	// the default tolerates a few empty reads
+	rd := NewReaderSize(&emptyReader{r: bytes.NewReader(bts), empty: 5}, 16)
+	all, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, bts) {
+		t.Fatal("bytes not equal")
+	}
+
+	rd = NewReaderSize(&emptyReader{r: bytes.NewReader(bts), empty: 5}, 16)
+	rd.SetMaxEmptyReads(5)
+	if _, err := rd.ReadByte(); err != io.ErrNoProgress {
+		t.Fatalf("expected %q; got %v", io.ErrNoProgress, err)
+	}
+	rd.SetMaxEmptyReads(6)
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[0] {
+		t.Fatalf("expected %d; got %d", bts[0], b)
+	}
+
+	// the default still gives up eventually
+	rd = NewReaderSize(&emptyReader{r: bytes.NewReader(bts), empty: DefaultMaxEmptyReads}, 16)
+	if _, err := rd.Peek(1); err != io.ErrNoProgress {
+		t.Fatalf("expected %q; got %v", io.ErrNoProgress, err)
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	if rd.Cap() != 64 || rd.Available() != 64 {
+		t.Fatalf("expected Cap() and Available() to be %d; got %d and %d", 64, rd.Cap(), rd.Available())
+	}
+	rd.Peek(64)
+	if rd.Available() != 0 {
+		t.Fatalf("expected Available() to be 0; got %d", rd.Available())
+	}
+	rd.Skip(10)
+	if rd.Available() != 10 {
+		t.Fatalf("expected Available() to be %d; got %d", 10, rd.Available())
+	}
+	m := rd.Mark()
+	rd.Skip(10)
+	if rd.Available() != 10 {
+		t.Fatalf("expected Available() to be %d with a mark; got %d", 10, rd.Available())
+	}
+	rd.Unmark(m)
+	if rd.Available() != 20 {
+		t.Fatalf("expected Available() to be %d; got %d", 20, rd.Available())
+	}
+}
+
+func TestPushBack(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+
+	// nothing consumed; has to grow at the front
+	if err := rd.PushBack([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	head, err := rd.Next(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(head) != "hello" {
+		t.Fatalf("expected %q; got %q", "hello", head)
+	}
+
+	// the pushed-back bytes replace consumed bytes;
+	// the first push moved Offset() back to -5
+	rd.Next(20)
+	rd.PushBack([]byte("xyz"))
+	if rd.Offset() != 17 {
+		t.Fatalf("expected Offset() to be %d; got %d", 17, rd.Offset())
+	}
+	out := make([]byte, 13)
+	if _, err := rd.ReadFull(out); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte("xyz"), bts[20:30]...)
+	if !bytes.Equal(out, want) {
+		t.Fatalf("expected %v; got %v", want, out)
+	}
+
+	// larger than the buffer
+	big := randomBts(500)
+	rd.PushBack(big)
+	out = make([]byte, 510)
+	if _, err := rd.ReadFull(out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, append(big, bts[30:40]...)) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestReadCString(t *testing.T) {
+	in := "first\x00\x00" + strings.Repeat("x", 100) + "\x00tail"
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+
+	for _, want := range []string{"first", "", strings.Repeat("x", 100)} {
+		s, err := rd.ReadCString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != want {
+			t.Fatalf("expected %q; got %q", want, s)
+		}
+	}
+	s, err := rd.ReadCString()
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if s != "tail" {
+		t.Fatalf("expected %q; got %q", "tail", s)
+	}
+	if _, err = rd.ReadCString(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+
+	rd = NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+	rd.SetMaxTokenSize(5)
+	if s, err = rd.ReadCString(); err != nil || s != "first" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+	rd.ReadCString()
+	s, err = rd.ReadCString()
+	if err != ErrTokenTooLong {
+		t.Fatalf("expected ErrTokenTooLong; got %v", err)
+	}
+	if s != "xxxxx" {
+		t.Fatalf("expected %q; got %q", "xxxxx", s)
+	}
+	if rd.Offset() != 12 {
+		t.Fatalf("expected Offset() to be %d; got %d", 12, rd.Offset())
+	}
+}
+
+func TestReadSlice(t *testing.T) {
+	in := "short\n" + strings.Repeat("x", 40) + "\nend"
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+
+	line, err := rd.ReadSlice('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(line) != "short\n" {
+		t.Fatalf("expected %q; got %q", "short\n", line)
+	}
+
+	// the long line doesn't fit; the buffer must not grow
+	var long []byte
+	for {
+		line, err = rd.ReadSlice('\n')
+		long = append(long, line...)
+		if err != ErrBufferFull {
+			break
+		}
+		if rd.BufferSize() != 16 {
+			t.Fatalf("expected BufferSize() to stay %d; got %d", 16, rd.BufferSize())
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(long) != strings.Repeat("x", 40)+"\n" {
+		t.Fatalf("unexpected line %q", long)
+	}
+
+	line, err = rd.ReadSlice('\n')
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if string(line) != "end" {
+		t.Fatalf("expected %q; got %q", "end", line)
+	}
+}
+
+func TestPeekRealloc(t *testing.T) {
+	bts := randomBts(200)
+	rd := NewReaderSize(bytes.NewReader(bts), 16)
+
+	// consume part of the buffer so that
+	// the realloc has to drop consumed bytes
+	if _, err := rd.Next(10); err != nil {
+		t.Fatal(err)
+	}
+	p, err := rd.Peek(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[10:50]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if rd.BufferSize() != 40 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 40, rd.BufferSize())
+	}
+	if rd.Offset() != 10 {
+		t.Fatalf("expected Offset() to be %d; got %d", 10, rd.Offset())
+	}
+
+	// with a mark pinning consumed bytes
+	m := rd.Mark()
+	rd.Next(30)
+	p, err = rd.Peek(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[40:140]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if err := rd.Rewind(m); err != nil {
+		t.Fatal(err)
+	}
+	p, _ = rd.Peek(130)
+	if !bytes.Equal(p, bts[10:140]) {
+		t.Fatal("rewound bytes not equal")
+	}
+}
+
+// oddSeeker is a bytes.Reader whose relative
+// seeks move 'skew' bytes further than requested,
+// and which never moves past the end of the data
+type oddSeeker struct {
+	*bytes.Reader
+	skew int64
+}
+
+func (o *oddSeeker) Seek(off int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent && off != 0 {
+		off += o.skew
+	}
+	pos, err := o.Reader.Seek(off, whence)
+	if err == nil && pos > o.Size() {
+		pos, err = o.Reader.Seek(0, io.SeekEnd)
+	}
+	return pos, err
+}
+
+func TestSkipSeekPastEnd(t *testing.T) {
+	f, err := ioutil.TempFile("", "fwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	bts := randomBts(1024)
+	if _, err := f.Write(bts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	// os.File seeks past the end without complaint
+	rd := NewReaderSize(f, 64)
+	rd.Peek(10)
+	n, err := rd.Skip(5000)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if n != 1024 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 1024, n)
+	}
+	if rd.Offset() != 1024 {
+		t.Fatalf("expected Offset() to be %d; got %d", 1024, rd.Offset())
+	}
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+
+	// a skip that fits is not affected
+	f.Seek(0, io.SeekStart)
+	rd.Reset(f)
+	if n, err := rd.Skip(1000); n != 1000 || err != nil {
+		t.Fatalf("expected (1000, <nil>); got (%d, %v)", n, err)
+	}
+	if b, err := rd.ReadByte(); err != nil || b != bts[1000] {
+		t.Fatalf("expected %d; got %d, %v", bts[1000], b, err)
+	}
+}
+
+func TestSkipSeekOdd(t *testing.T) {
+	bts := randomBts(1024)
+
+	// clamps at the end of the stream
+	rd := NewReaderSize(&oddSeeker{Reader: bytes.NewReader(bts)}, 64)
+	rd.Peek(10)
+	n, err := rd.Skip(2000)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if n != 1024 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 1024, n)
+	}
+	if rd.Offset() != 1024 {
+		t.Fatalf("expected Offset() to be %d; got %d", 1024, rd.Offset())
+	}
+
+	// overshoots by a few bytes
+	rd = NewReaderSize(&oddSeeker{Reader: bytes.NewReader(bts), skew: 7}, 64)
+	rd.Peek(10)
+	n, err = rd.Skip(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 500 {
+		t.Fatalf("expected to skip %d bytes; skipped %d", 500, n)
+	}
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[500] {
+		t.Fatalf("expected %d; got %d", bts[500], b)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	in := strings.Repeat("x", 64) + "\n"
+
+	// ErrBufferFull is distinguishable from EOF
+	rd := NewReaderSize(strings.NewReader(in), 16)
+	if _, err := rd.ReadSlice('\n'); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull; got %v", err)
+	}
+
+	// a wrapped ErrNeedMore asks for more data
+	rd = NewReaderSize(strings.NewReader(in), 16)
+	var lines int
+	err := rd.ScanBytes(func(b []byte) (int, error) {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			return 0, fmt.Errorf("line of %d bytes: %w", len(b), ErrNeedMore)
+		}
+		lines++
+		return i + 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != 1 {
+		t.Fatalf("expected %d line; got %d", 1, lines)
+	}
+}
+
+func TestReadFullEOF(t *testing.T) {
+	rd := NewReaderSize(partialReader{bytes.NewReader(randomBts(100))}, 16)
+	out := make([]byte, 60)
+	if _, err := rd.ReadFull(out); err != nil {
+		t.Fatal(err)
+	}
+
+	// partial read
+	n, err := rd.ReadFull(out)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if n != 40 {
+		t.Fatalf("expected to read %d bytes; read %d", 40, n)
+	}
+
+	// nothing left to read
+	n, err = rd.ReadFull(out)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected to read %d bytes; read %d", 0, n)
+	}
+
+	// an error that is already pending
+	// does not hide the buffered bytes
+	boom := errors.New("boom")
+	rd = NewReaderSize(&stepReader{{"abc", boom}}, 16)
+	rd.Peek(1)
+	n, err = rd.ReadFull(out)
+	if err != boom || n != 3 || !bytes.Equal(out[:n], []byte("abc")) {
+		t.Fatalf("expected (3, %v) from ReadFull(); got (%d, %v)", boom, n, err)
+	}
+}
+
+func TestCopyN(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.Peek(10)
+
+	var buf bytes.Buffer
+	n, err := rd.CopyN(&buf, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 300 || !bytes.Equal(buf.Bytes(), bts[:300]) {
+		t.Fatalf("expected to copy the first %d bytes; copied %d", 300, n)
+	}
+	if rd.Offset() != 300 {
+		t.Fatalf("expected Offset() to be %d; got %d", 300, rd.Offset())
+	}
+
+	// more than remains
+	buf.Reset()
+	n, err = rd.CopyN(&buf, 1000)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if n != 700 || !bytes.Equal(buf.Bytes(), bts[300:]) {
+		t.Fatalf("expected to copy the last %d bytes; copied %d", 700, n)
+	}
+
+	// failing writer
+	rd = NewReaderSize(bytes.NewReader(bts), 64)
+	boom := errors.New("boom")
+	n, err = rd.CopyN(&errWriter{err: boom}, 100)
+	if err != boom {
+		t.Fatalf("expected %v; got %v", boom, err)
+	}
+	if n != 0 {
+		t.Fatalf("expected to copy %d bytes; copied %d", 0, n)
+	}
+}
+
+// smallWriter fails writes larger than 'max'
+// and accepts only 'max' bytes from the write
+// at which 'fail' drops to zero
+type smallWriter struct {
+	buf  bytes.Buffer
+	max  int
+	fail int
+}
+
+func (s *smallWriter) Write(p []byte) (int, error) {
+	if len(p) > s.max {
+		return 0, io.ErrShortBuffer
+	}
+	if s.fail--; s.fail == 0 {
+		n := len(p) / 2
+		s.buf.Write(p[:n])
+		return n, io.ErrClosedPipe
+	}
+	return s.buf.Write(p)
+}
+
+func TestWriteToChunks(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(bytes.NewReader(bts), 128)
+	w := &smallWriter{max: 10}
+	if _, err := rd.WriteTo(w); err != io.ErrShortBuffer {
+		t.Fatalf("expected io.ErrShortBuffer; got %v", err)
+	}
+
+	rd = NewReaderSize(bytes.NewReader(bts), 128)
+	rd.SetWriteChunkSize(10)
+	n, err := rd.WriteTo(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 || !bytes.Equal(w.buf.Bytes(), bts) {
+		t.Fatalf("expected to write %d bytes; wrote %d", 1000, n)
+	}
+
+	// WriteToN stops at the limit
+	rd = NewReaderSize(bytes.NewReader(bts), 128)
+	w = &smallWriter{max: 1000}
+	n, err = rd.WriteToN(w, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 300 || rd.Offset() != 300 {
+		t.Fatalf("expected to write %d bytes; wrote %d", 300, n)
+	}
+	n, err = rd.WriteToN(w, 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 700 || !bytes.Equal(w.buf.Bytes(), bts) {
+		t.Fatalf("expected to write %d bytes; wrote %d", 700, n)
+	}
+
+	// a partial write is counted
+	// and the error is surfaced
+	rd = NewReaderSize(bytes.NewReader(bts), 128)
+	rd.SetWriteChunkSize(10)
+	w = &smallWriter{max: 10, fail: 3}
+	n, err = rd.WriteTo(w)
+	if err != io.ErrClosedPipe {
+		t.Fatalf("expected io.ErrClosedPipe; got %v", err)
+	}
+	if n != 25 || rd.Offset() != 25 {
+		t.Fatalf("expected to write %d bytes; wrote %d (offset %d)", 25, n, rd.Offset())
+	}
+
+	// a read error is surfaced
+	boom := errors.New("boom")
+	rd = NewReaderSize(&errReader{r: bytes.NewReader(bts), err: boom}, 128)
+	n, err = rd.WriteTo(ioutil.Discard)
+	if err != boom {
+		t.Fatalf("expected %v; got %v", boom, err)
+	}
+	if n != 1000 {
+		t.Fatalf("expected to write %d bytes; wrote %d", 1000, n)
+	}
+}
+
+func TestByteAt(t *testing.T) {
+	bts := randomBts(100)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+	rd.Next(10)
+	for _, off := range []int{0, 5, 40, 89} {
+		b, err := rd.ByteAt(off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[10+off] {
+			t.Fatalf("at offset %d: expected %d; got %d", off, bts[10+off], b)
+		}
+	}
+	if rd.Offset() != 10 {
+		t.Fatalf("expected Offset() to be %d; got %d", 10, rd.Offset())
+	}
+	if _, err := rd.ByteAt(90); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if _, err := rd.ByteAt(-1); err != bufio.ErrNegativeCount {
+		t.Fatalf("expected bufio.ErrNegativeCount; got %v", err)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	bts := randomBts(500)
+	rd := NewReaderSize(bytes.NewReader(bts), 16)
+	var _ io.ReaderAt = rd
+
+	rd.Next(10)
+	out := make([]byte, 50)
+	n, err := rd.ReadAt(out, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 50 || !bytes.Equal(out, bts[300:350]) {
+		t.Fatal("bytes not equal")
+	}
+	if n, err = rd.ReadAt(out, 480); err != io.EOF || n != 20 {
+		t.Fatalf("expected (%d, io.EOF); got (%d, %v)", 20, n, err)
+	}
+
+	// the forward position is undisturbed
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[10] {
+		t.Fatalf("expected %d; got %d", bts[10], b)
+	}
+
+	rd.Reset(partialReader{bytes.NewReader(bts)})
+	if _, err := rd.ReadAt(out, 0); err != ErrNotReaderAt {
+		t.Fatalf("expected ErrNotReaderAt; got %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+	rd.Next(5)
+
+	a := rd.Snapshot()
+	rd.Next(100)
+	b := rd.Snapshot()
+	rd.Next(300)
+
+	// restoring the later snapshot keeps the earlier one
+	if err := rd.Restore(b); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Offset() != 105 {
+		t.Fatalf("expected Offset() to be %d; got %d", 105, rd.Offset())
+	}
+	rd.Next(500)
+	if err := rd.Restore(a); err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 700)
+	if _, err := rd.ReadFull(out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[5:705]) {
+		t.Fatal("restored bytes not equal")
+	}
+
+	// both snapshots have been released
+	if err := rd.Restore(a); err != ErrInvalidMark {
+		t.Fatalf("expected ErrInvalidMark; got %v", err)
+	}
+	if err := rd.DropSnapshot(b); err != ErrInvalidMark {
+		t.Fatalf("expected ErrInvalidMark; got %v", err)
+	}
+
+	c := rd.Snapshot()
+	if err := rd.DropSnapshot(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.Restore(c); err != ErrInvalidMark {
+		t.Fatalf("expected ErrInvalidMark; got %v", err)
+	}
+	if rd.Offset() != 705 {
+		t.Fatalf("expected Offset() to be %d; got %d", 705, rd.Offset())
+	}
+}
+
+func TestReads(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(bytes.NewReader(bts), 100)
+	rd.Next(50)
+	if rd.Reads() != 1 || rd.BytesFilled() != 100 {
+		t.Fatalf("expected (%d, %d); got (%d, %d)", 1, 100, rd.Reads(), rd.BytesFilled())
+	}
+
+	// a direct read into a large slice
+	out := make([]byte, 400)
+	rd.ReadFull(out)
+	if rd.Reads() != 2 || rd.BytesFilled() != 450 {
+		t.Fatalf("expected (%d, %d); got (%d, %d)", 2, 450, rd.Reads(), rd.BytesFilled())
+	}
+
+	// skipping by seeking doesn't read
+	rd.Skip(300)
+	if rd.Reads() != 2 {
+		t.Fatalf("expected %d reads; got %d", 2, rd.Reads())
+	}
+
+	// the final read returns io.EOF
+	rd.ReadAll()
+	if rd.Reads() != 6 || rd.BytesFilled() != 700 {
+		t.Fatalf("expected (%d, %d); got (%d, %d)", 6, 700, rd.Reads(), rd.BytesFilled())
+	}
+
+	rd.Reset(bytes.NewReader(bts))
+	if rd.Reads() != 0 || rd.BytesFilled() != 0 {
+		t.Fatalf("expected Reset() to zero the counters; got (%d, %d)", rd.Reads(), rd.BytesFilled())
+	}
+}
+
+func TestReadFixed(t *testing.T) {
+	bts := randomBts(300)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+
+	scratch := make([]byte, 0, 128)
+	out, err := rd.ReadFixed(100, scratch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[:100]) {
+		t.Fatal("bytes not equal")
+	}
+	if &out[0] != &scratch[:1][0] {
+		t.Fatal("expected ReadFixed to use the supplied buffer")
+	}
+
+	// appends, growing as needed
+	out, err = rd.ReadFixed(150, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[:250]) {
+		t.Fatal("bytes not equal")
+	}
+
+	out, err = rd.ReadFixed(100, out[:0])
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if !bytes.Equal(out, bts[250:]) {
+		t.Fatal("bytes not equal")
+	}
+	if _, err = rd.ReadFixed(1, nil); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+
+	// the size is not allocated up front
+	rd.Reset(bytes.NewReader(bts))
+	out, err = rd.ReadFixed(int(^uint(0)>>1), []byte("x"))
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if !bytes.Equal(out, append([]byte("x"), bts...)) {
+		t.Fatal("bytes not equal")
+	}
+
+	// a large read grows in chunks
+	big := randomBts(5 * readChunk)
+	rd.Reset(partialReader{bytes.NewReader(big)})
+	out, err = rd.ReadFixed(len(big), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, big) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	bts := randomBts(200)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Next(40)
+	if rd.Available() != 40 {
+		t.Fatalf("expected Available() to be %d; got %d", 40, rd.Available())
+	}
+	rd.Compact()
+	if rd.n != 0 || len(rd.data) != 24 {
+		t.Fatalf("expected the live bytes at the front; got data[%d:%d]", rd.n, len(rd.data))
+	}
+	if rd.Offset() != 40 {
+		t.Fatalf("expected Offset() to be %d; got %d", 40, rd.Offset())
+	}
+
+	// no realloc is needed now
+	p, err := rd.Peek(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[40:104]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if rd.BufferSize() != 64 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 64, rd.BufferSize())
+	}
+
+	// marked bytes are retained
+	m := rd.Mark()
+	rd.Next(10)
+	rd.Compact()
+	if rd.n != 10 {
+		t.Fatalf("expected the read offset to be %d; got %d", 10, rd.n)
+	}
+	if err := rd.Rewind(m); err != nil {
+		t.Fatal(err)
+	}
+	p, _ = rd.Peek(10)
+	if !bytes.Equal(p, bts[40:50]) {
+		t.Fatal("rewound bytes not equal")
+	}
+}
+
+func TestReadUntilAny(t *testing.T) {
+	in := "a,bb;" + strings.Repeat("c", 40) + "\n\xffdd\xfftail"
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+	delims := []byte{',', ';', '\n', 0xff}
+
+	want := []struct {
+		field string
+		delim byte
+	}{
+		{"a,", ','},
+		{"bb;", ';'},
+		{strings.Repeat("c", 40) + "\n", '\n'},
+		{"\xff", 0xff},
+		{"dd\xff", 0xff},
+	}
+	for _, w := range want {
+		field, delim, err := rd.ReadUntilAny(delims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(field) != w.field || delim != w.delim {
+			t.Fatalf("expected (%q, %q); got (%q, %q)", w.field, w.delim, field, delim)
+		}
+	}
+	field, delim, err := rd.ReadUntilAny(delims)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if string(field) != "tail" || delim != 0 {
+		t.Fatalf("expected (%q, 0); got (%q, %q)", "tail", field, delim)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	rd := NewReaderSize(partialReader{strings.NewReader("PK\x03\x04" + strings.Repeat("z", 30) + "end")}, 16)
+
+	ok, err := rd.Consume([]byte("PK\x05\x06"))
+	if ok || err != nil {
+		t.Fatalf("expected (false, nil); got (%v, %v)", ok, err)
+	}
+	ok, err = rd.Consume([]byte("PK\x03\x04"))
+	if !ok || err != nil {
+		t.Fatalf("expected (true, nil); got (%v, %v)", ok, err)
+	}
+	ok, err = rd.Consume([]byte(strings.Repeat("z", 30)))
+	if !ok || err != nil {
+		t.Fatalf("expected (true, nil); got (%v, %v)", ok, err)
+	}
+	if rd.Offset() != 34 {
+		t.Fatalf("expected Offset() to be %d; got %d", 34, rd.Offset())
+	}
+
+	// too short
+	ok, err = rd.Consume([]byte("ending"))
+	if ok || err != io.EOF {
+		t.Fatalf("expected (false, io.EOF); got (%v, %v)", ok, err)
+	}
+	rest, _ := rd.ReadAll()
+	if string(rest) != "end" {
+		t.Fatalf("expected %q to remain; got %q", "end", rest)
+	}
+}
+
+func TestUnderlying(t *testing.T) {
+	src := bytes.NewReader(randomBts(10))
+	rd := NewReader(src)
+	if rd.Underlying() != src {
+		t.Fatal("expected Underlying() to return the reader passed to NewReader")
+	}
+	other := strings.NewReader("x")
+	rd.Reset(other)
+	if rd.Underlying() != other {
+		t.Fatal("expected Underlying() to return the reader passed to Reset")
+	}
+}
+
+func TestHash(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Next(10)
+
+	h := crc32.NewIEEE()
+	rd.Hash(h)
+	rd.Peek(100)
+	rd.Next(50)
+	m := rd.Mark()
+	rd.Next(40)
+	rd.Rewind(m)
+	rd.Unmark(m)
+	// would otherwise seek
+	if _, err := rd.Skip(500); err != nil {
+		t.Fatal(err)
+	}
+	rd.ReadByte()
+	if h.Sum32() != crc32.ChecksumIEEE(bts[10:561]) {
+		t.Fatal("checksum doesn't match the consumed bytes")
+	}
+
+	rd.Hash(nil)
+	rd.Next(100)
+	if h.Sum32() != crc32.ChecksumIEEE(bts[10:561]) {
+		t.Fatal("checksum changed after Hash(nil)")
+	}
+}
+
+func TestLen(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	if n, ok := rd.Len(); !ok || n != 1000 {
+		t.Fatalf("expected (%d, true); got (%d, %v)", 1000, n, ok)
+	}
+	rd.Next(10)
+	if n, ok := rd.Len(); !ok || n != 990 {
+		t.Fatalf("expected (%d, true); got (%d, %v)", 990, n, ok)
+	}
+	rd.Skip(500)
+	rd.Limit(100)
+	if n, ok := rd.Len(); !ok || n != 100 {
+		t.Fatalf("expected (%d, true); got (%d, %v)", 100, n, ok)
+	}
+
+	// Len doesn't disturb the stream
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[510] {
+		t.Fatalf("expected %d; got %d", bts[510], b)
+	}
+
+	rd.Reset(partialReader{bytes.NewReader(bts)})
+	if n, ok := rd.Len(); ok || n != 0 {
+		t.Fatalf("expected (0, false); got (%d, %v)", n, ok)
+	}
+}
+
+func TestReadBlock(t *testing.T) {
+	bts := randomBts(100)
+	rd := NewReaderSize(bytes.NewReader(bts), 16)
+
+	a, err := rd.ReadBlock(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := rd.ReadBlock(60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 60 || cap(b) != 60 {
+		t.Fatalf("expected an exact-size slice; got len %d cap %d", len(b), cap(b))
+	}
+	// later reads don't disturb earlier blocks
+	rd.Next(20)
+	if !bytes.Equal(a, bts[:10]) || !bytes.Equal(b, bts[10:70]) {
+		t.Fatal("blocks not equal")
+	}
+
+	c, err := rd.ReadBlock(20)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if !bytes.Equal(c, bts[90:]) {
+		t.Fatal("short block not equal")
+	}
+}
+
+func TestZeroAndNegativeCounts(t *testing.T) {
+	rd := NewReaderSize(bytes.NewReader(randomBts(10)), 16)
+	rd.Next(10)
+	rd.Peek(1) // hits io.EOF
+
+	if b, err := rd.Peek(0); len(b) != 0 || err != nil {
+		t.Fatalf("Peek(0): expected (empty, nil); got (%d bytes, %v)", len(b), err)
+	}
+	if b, err := rd.Next(0); len(b) != 0 || err != nil {
+		t.Fatalf("Next(0): expected (empty, nil); got (%d bytes, %v)", len(b), err)
+	}
+	if n, err := rd.Skip(0); n != 0 || err != nil {
+		t.Fatalf("Skip(0): expected (0, nil); got (%d, %v)", n, err)
+	}
+	if n, err := rd.Discard(0); n != 0 || err != nil {
+		t.Fatalf("Discard(0): expected (0, nil); got (%d, %v)", n, err)
+	}
+
+	if _, err := rd.Peek(-1); err != bufio.ErrNegativeCount {
+		t.Fatalf("Peek(-1): expected bufio.ErrNegativeCount; got %v", err)
+	}
+	if _, err := rd.Next(-1); err != bufio.ErrNegativeCount {
+		t.Fatalf("Next(-1): expected bufio.ErrNegativeCount; got %v", err)
+	}
+	if _, err := rd.Discard(-1); err != bufio.ErrNegativeCount {
+		t.Fatalf("Discard(-1): expected bufio.ErrNegativeCount; got %v", err)
+	}
+	// Skip(-n) moves backwards
+	if n, err := rd.Skip(-3); n != -3 || err != nil {
+		t.Fatalf("Skip(-3): expected (-3, nil); got (%d, %v)", n, err)
+	}
+	if rd.Offset() != 7 {
+		t.Fatalf("expected Offset() to be %d; got %d", 7, rd.Offset())
+	}
+}
+
+func TestDrain(t *testing.T) {
+	bts := randomBts(1000)
+
+	// seeking
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Next(10)
+	n, err := rd.Drain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 990 || rd.Offset() != 1000 || rd.Reads() != 1 {
+		t.Fatalf("expected to drain %d bytes with 1 read; drained %d with %d", 990, n, rd.Reads())
+	}
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+
+	// seeking up to a limit
+	rd = NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Limit(300)
+	if n, err = rd.Drain(); n != 300 || err != nil {
+		t.Fatalf("expected (%d, nil); got (%d, %v)", 300, n, err)
+	}
+
+	// reading
+	rd = NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.Next(10)
+	if n, err = rd.Drain(); n != 990 || err != nil {
+		t.Fatalf("expected (%d, nil); got (%d, %v)", 990, n, err)
+	}
+
+	boom := errors.New("boom")
+	rd = NewReaderSize(&errReader{r: bytes.NewReader(bts), err: boom}, 64)
+	if n, err = rd.Drain(); n != 1000 || err != boom {
+		t.Fatalf("expected (%d, %v); got (%d, %v)", 1000, boom, n, err)
+	}
+}
+
+func TestBufferedReader(t *testing.T) {
+	in := "first line\nsecond\n"
+	for _, br := range []BufferedReader{
+		NewReaderSize(strings.NewReader(in), 16),
+		bufio.NewReaderSize(strings.NewReader(in), 16),
+	} {
+		if br.Size() != 16 {
+			t.Fatalf("%T: expected Size() to be %d; got %d", br, 16, br.Size())
+		}
+		line, err := br.ReadSlice('\n')
+		if err != nil {
+			t.Fatalf("%T: %v", br, err)
+		}
+		if string(line) != "first line\n" {
+			t.Fatalf("%T: expected %q; got %q", br, "first line\n", line)
+		}
+		r, _, err := br.ReadRune()
+		if err != nil || r != 's' {
+			t.Fatalf("%T: expected ('s', nil); got (%q, %v)", br, r, err)
+		}
+		if err := br.UnreadRune(); err != nil {
+			t.Fatalf("%T: %v", br, err)
+		}
+		s, err := br.ReadString('\n')
+		if err != nil || s != "second\n" {
+			t.Fatalf("%T: expected (%q, nil); got (%q, %v)", br, "second\n", s, err)
+		}
+	}
+}
+
+func TestMaxBufferSize(t *testing.T) {
+	in := strings.Repeat("x", 200) + "\n"
+	rd := NewReaderSize(strings.NewReader(in), 16)
+	rd.SetMaxBufferSize(64)
+
+	if _, err := rd.Peek(65); err != ErrBufferLimitExceeded {
+		t.Fatalf("Peek: expected ErrBufferLimitExceeded; got %v", err)
+	}
+	if _, err := rd.Next(100); err != ErrBufferLimitExceeded {
+		t.Fatalf("Next: expected ErrBufferLimitExceeded; got %v", err)
+	}
+	if _, err := rd.PeekUntil('\n'); err != ErrBufferLimitExceeded {
+		t.Fatalf("PeekUntil: expected ErrBufferLimitExceeded; got %v", err)
+	}
+	if rd.BufferSize() != 64 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 64, rd.BufferSize())
+	}
+	if err := rd.PushBack(make([]byte, 100)); err != ErrBufferLimitExceeded {
+		t.Fatalf("PushBack: expected ErrBufferLimitExceeded; got %v", err)
+	}
+	grow := NewReaderSize(strings.NewReader(in), 16)
+	grow.SetMaxBufferSize(64)
+	grow.Grow(100)
+	if _, err := grow.ReadByte(); err != ErrBufferLimitExceeded {
+		t.Fatalf("Grow: expected ErrBufferLimitExceeded; got %v", err)
+	}
+
+	// within the limit, nothing changes
+	p, err := rd.Peek(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != in[:64] {
+		t.Fatalf("expected %q; got %q", in[:64], p)
+	}
+	// ReadBytes doesn't need to grow the buffer
+	line, err := rd.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(line) != in {
+		t.Fatal("line not equal")
+	}
+
+	// unlimited again
+	rd.Reset(strings.NewReader(in))
+	rd.SetMaxBufferSize(0)
+	if _, err := rd.Peek(150); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnsureCap(t *testing.T) {
+	bts := randomBts(500)
+
+	// via Peek
+	rd := NewReaderSize(bytes.NewReader(bts), 16)
+	rd.Next(5)
+	p, err := rd.Peek(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[5:55]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if rd.n != 0 || rd.BufferSize() != 50 {
+		t.Fatalf("expected r.n = 0 and BufferSize() = %d; got %d and %d", 50, rd.n, rd.BufferSize())
+	}
+
+	// via Next, with a mark pinning 10 bytes
+	m := rd.Mark()
+	rd.Next(10)
+	b, err := rd.Next(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, bts[15:115]) {
+		t.Fatal("bytes from Next() not equal")
+	}
+	if rd.BufferSize() != 110 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 110, rd.BufferSize())
+	}
+	rd.Rewind(m)
+	if rd.n != 0 {
+		t.Fatalf("expected the mark at the front of the buffer; got r.n = %d", rd.n)
+	}
+	b, _ = rd.Next(110)
+	if !bytes.Equal(b, bts[5:115]) {
+		t.Fatal("rewound bytes not equal")
+	}
+}
+
+func TestSeekThreshold(t *testing.T) {
+	bts := randomBts(2000)
+	rd := NewReaderSize(bytes.NewReader(bts), 100)
+	rd.Peek(1)
+
+	// a short skip reads through the buffer
+	// and keeps the bytes read along the way
+	if _, err := rd.Skip(150); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Reads() != 2 || rd.Buffered() != 50 {
+		t.Fatalf("expected 2 reads and 50 buffered bytes; got %d and %d", rd.Reads(), rd.Buffered())
+	}
+
+	// a long one seeks
+	if _, err := rd.Skip(500); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Reads() != 2 {
+		t.Fatalf("expected 2 reads; got %d", rd.Reads())
+	}
+
+	rd.SetSeekThreshold(1)
+	rd.Peek(1)
+	if _, err := rd.Skip(110); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Reads() != 3 {
+		t.Fatalf("expected 3 reads; got %d", rd.Reads())
+	}
+	b, _ := rd.ReadByte()
+	if b != bts[760] {
+		t.Fatalf("expected %d; got %d", bts[760], b)
+	}
+}
+
+func TestSetReadAhead(t *testing.T) {
+	bts := randomBts(5000)
+	rd := NewReaderSize(bytes.NewReader(bts), 1024)
+	rd.SetReadAhead(16)
+
+	if _, err := rd.Peek(2); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Reads() != 1 || rd.Buffered() != 16 {
+		t.Fatalf("expected 1 read and 16 buffered bytes; got %d and %d", rd.Reads(), rd.Buffered())
+	}
+
+	// larger requests take several reads
+	if _, err := rd.Peek(40); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Reads() != 3 || rd.Buffered() != 48 {
+		t.Fatalf("expected 3 reads and 48 buffered bytes; got %d and %d", rd.Reads(), rd.Buffered())
+	}
+
+	// back to filling the whole buffer
+	rd.SetReadAhead(0)
+	if _, err := rd.Peek(100); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Buffered() != 1024 {
+		t.Fatalf("expected 1024 buffered bytes; got %d", rd.Buffered())
+	}
+
+	rd.SetReadAhead(7)
+	out, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestReadStringN(t *testing.T) {
+	in := "\x05hello\x2a" + strings.Repeat("y", 42) + "\x10short"
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+
+	for _, want := range []string{"hello", strings.Repeat("y", 42)} {
+		l, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := rd.ReadStringN(int(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != want {
+			t.Fatalf("expected %q; got %q", want, s)
+		}
+	}
+	l, _ := rd.ReadByte()
+	s, err := rd.ReadStringN(int(l))
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if s != "short" {
+		t.Fatalf("expected %q; got %q", "short", s)
+	}
+	if _, err := rd.ReadStringN(-1); err != bufio.ErrNegativeCount {
+		t.Fatalf("expected bufio.ErrNegativeCount; got %v", err)
+	}
+
+	// the length is not allocated up front
+	rd.Reset(strings.NewReader("short"))
+	s, err = rd.ReadStringN(int(^uint(0) >> 1))
+	if err != io.ErrUnexpectedEOF || s != "short" {
+		t.Fatalf("expected (%q, io.ErrUnexpectedEOF); got (%q, %v)", "short", s, err)
+	}
+}
+
+func TestEmptyReadError(t *testing.T) {
+	errEmpty := errors.New("try again later")
+	bts := randomBts(100)
+	rd := NewReaderSize(&emptyReader{r: bytes.NewReader(bts), empty: 5}, 16)
+	rd.SetMaxEmptyReads(3)
+	rd.SetEmptyReadError(errEmpty)
+
+	out := make([]byte, 100)
+	n := 0
+	for n < 100 {
+		nn, err := rd.Read(out[n:])
+		n += nn
+		if err != nil && err != errEmpty {
+			t.Fatalf("expected %v; got %v", errEmpty, err)
+		}
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("bytes not equal")
+	}
+
+	rd.SetEmptyReadError(nil)
+	rd.ResetKeepOptions(&emptyReader{r: bytes.NewReader(bts), empty: 5})
+	if _, err := rd.ReadByte(); err != io.ErrNoProgress {
+		t.Fatalf("expected io.ErrNoProgress; got %v", err)
+	}
+}
+
+// seekOnly hides every method
+// of a bytes.Reader except
+// Read and Seek
+type seekOnly struct {
+	r *bytes.Reader
+}
+
+func (s seekOnly) Read(p []byte) (int, error) { return s.r.Read(p) }
+
+func (s seekOnly) Seek(off int64, whence int) (int64, error) { return s.r.Seek(off, whence) }
+
+func TestPeekAt(t *testing.T) {
+	bts := randomBts(1000)
+	for _, src := range []io.Reader{bytes.NewReader(bts), seekOnly{bytes.NewReader(bts)}} {
+		rd := NewReaderSize(src, 64)
+		rd.Next(10)
+
+		footer := make([]byte, 20)
+		n, err := rd.PeekAt(footer, 980)
+		if err != nil {
+			t.Fatalf("%T: %v", src, err)
+		}
+		if n != 20 || !bytes.Equal(footer, bts[980:]) {
+			t.Fatalf("%T: footer not equal", src)
+		}
+		if n, err = rd.PeekAt(footer, 990); n != 10 || err != io.EOF {
+			t.Fatalf("%T: expected (%d, io.EOF); got (%d, %v)", src, 10, n, err)
+		}
+
+		// forward reads are unaffected
+		out := make([]byte, 200)
+		if _, err := rd.ReadFull(out); err != nil {
+			t.Fatalf("%T: %v", src, err)
+		}
+		if !bytes.Equal(out, bts[10:210]) {
+			t.Fatalf("%T: bytes not equal", src)
+		}
+	}
+
+	rd := NewReader(partialReader{bytes.NewReader(bts)})
+	if _, err := rd.PeekAt(make([]byte, 1), 0); err != ErrNotSeeker {
+		t.Fatalf("expected ErrNotSeeker; got %v", err)
+	}
+}
+
+func TestPeekN(t *testing.T) {
+	bts := randomBts(50)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+
+	fields, err := rd.PeekN(4, 2, 0, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := 0
+	for i, n := range []int{4, 2, 0, 30} {
+		if !bytes.Equal(fields[i], bts[off:off+n]) {
+			t.Fatalf("field %d not equal", i)
+		}
+		off += n
+	}
+	if rd.Offset() != 0 {
+		t.Fatalf("expected Offset() to be 0; got %d", rd.Offset())
+	}
+
+	// short
+	fields, err = rd.PeekN(20, 20, 20)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("expected %d complete fields; got %d", 2, len(fields))
+	}
+
+	if _, err = rd.PeekN(1, -1); err != bufio.ErrNegativeCount {
+		t.Fatalf("expected bufio.ErrNegativeCount; got %v", err)
+	}
+}
+
+func TestShrinkTo(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Next(500)
+	if rd.BufferSize() < 500 {
+		t.Fatalf("expected the buffer to grow; BufferSize() is %d", rd.BufferSize())
+	}
+
+	rd.Peek(1)
+	buffered := rd.Buffered()
+	rd.ShrinkTo(64)
+	if rd.BufferSize() != max(64, buffered) {
+		t.Fatalf("expected BufferSize() to be %d; got %d", max(64, buffered), rd.BufferSize())
+	}
+	rest, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts[500:]) {
+		t.Fatal("bytes not equal")
+	}
+
+	// no-op when already small enough
+	rd.Reset(bytes.NewReader(bts))
+	size := rd.BufferSize()
+	rd.ShrinkTo(size + 10)
+	if rd.BufferSize() != size {
+		t.Fatalf("expected BufferSize() to stay %d; got %d", size, rd.BufferSize())
+	}
+}
+
+// BenchmarkReadFullBuffered measures ReadFull calls
+// that are mostly satisfied by the buffer; reads/op
+// counts the reads made on the source, which should
+// be one per buffer's worth of calls
+func BenchmarkReadFullBuffered(b *testing.B) {
+	bts := randomBts(64 * 1024)
+	src := &readCounter{r: bytes.NewReader(bts)}
+	rd := NewReaderSize(src, 64*1024)
+	out := make([]byte, 16)
+	b.SetBytes(int64(len(out)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := rd.ReadFull(out); err != nil {
+			src.r = bytes.NewReader(bts)
+			rd.Reset(src)
+		}
+	}
+	b.ReportMetric(float64(src.count)/float64(b.N), "reads/op")
+}
+
+func TestPeekAvailable(t *testing.T) {
+	bts := randomBts(100)
+	rd := NewReaderSize(bytes.NewReader(bts), 32)
+	if b, ok := rd.PeekAvailable(4); ok || len(b) != 0 {
+		t.Fatalf("expected (empty, false); got (%d bytes, %v)", len(b), ok)
+	}
+	rd.Peek(1)
+	b, ok := rd.PeekAvailable(10)
+	if !ok || !bytes.Equal(b, bts[:10]) {
+		t.Fatalf("expected the first 10 bytes; got (%d bytes, %v)", len(b), ok)
+	}
+	b, ok = rd.PeekAvailable(50)
+	if ok || !bytes.Equal(b, bts[:32]) {
+		t.Fatalf("expected the 32 buffered bytes; got (%d bytes, %v)", len(b), ok)
+	}
+	if rd.Reads() != 1 {
+		t.Fatalf("expected %d read; got %d", 1, rd.Reads())
+	}
+}
+
+// readFromBuffer records calls to ReadFrom
+type readFromBuffer struct {
+	bytes.Buffer
+	calls int
+}
+
+func (r *readFromBuffer) ReadFrom(src io.Reader) (int64, error) {
+	r.calls++
+	return r.Buffer.ReadFrom(src)
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	bts := randomBts(5000)
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.Next(10)
+
+	var w readFromBuffer
+	n, err := rd.WriteTo(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4990 || !bytes.Equal(w.Bytes(), bts[10:]) {
+		t.Fatalf("expected to write %d bytes; wrote %d", 4990, n)
+	}
+	if w.calls != 1 {
+		t.Fatalf("expected %d call to ReadFrom; got %d", 1, w.calls)
+	}
+	if rd.Offset() != 5000 {
+		t.Fatalf("expected Offset() to be %d; got %d", 5000, rd.Offset())
+	}
+	// one fill plus the hand-off
+	if rd.Reads() != 2 || rd.BytesFilled() != 5000 {
+		t.Fatalf("expected 2 reads of 5000 bytes; got %d of %d", rd.Reads(), rd.BytesFilled())
+	}
+
+	// respects the limit
+	rd.Reset(bytes.NewReader(bts))
+	rd.Limit(1000)
+	w = readFromBuffer{}
+	if n, err = rd.WriteTo(&w); n != 1000 || err != nil {
+		t.Fatalf("expected (%d, nil); got (%d, %v)", 1000, n, err)
+	}
+
+	// not seekable
+	rd.Reset(partialReader{bytes.NewReader(bts)})
+	w = readFromBuffer{}
+	if n, err = rd.WriteTo(&w); n != 5000 || err != nil {
+		t.Fatalf("expected (%d, nil); got (%d, %v)", 5000, n, err)
+	}
+	if w.calls != 0 {
+		t.Fatalf("expected no calls to ReadFrom; got %d", w.calls)
+	}
+}
+
+func TestResetKeepOptions(t *testing.T) {
+	bts := randomBts(500)
+	var tee bytes.Buffer
+	h := crc32.NewIEEE()
+	rd := NewReaderSize(bytes.NewReader(bts), 64)
+	rd.SetPromoteEOF(false)
+	rd.SetMaxBufferSize(128)
+	rd.EnablePositionTracking()
+	rd.Limit(100)
+	rd.Tee(&tee)
+	rd.Hash(h)
+	rd.ReadAll()
+
+	rd.ResetKeepOptions(bytes.NewReader(bts[100:]))
+	out, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[100:200]) {
+		t.Fatalf("expected the limit to be re-applied; read %d bytes", len(out))
+	}
+	if !bytes.Equal(tee.Bytes(), bts[:200]) {
+		t.Fatal("expected the tee to be kept")
+	}
+	if h.Sum32() != crc32.ChecksumIEEE(bts[:200]) {
+		t.Fatal("expected the hash to be kept")
+	}
+	if _, err := rd.Next(1); err != io.EOF {
+		t.Fatalf("expected the EOF policy to be kept; got %v", err)
+	}
+	if _, err := rd.Peek(200); err != ErrBufferLimitExceeded {
+		t.Fatalf("expected the buffer limit to be kept; got %v", err)
+	}
+	if line, _ := rd.Position(); line != 1+bytes.Count(bts[100:200], []byte{'\n'}) {
+		t.Fatalf("expected tracking to be kept and restarted; got line %d", line)
+	}
+
+	// Reset clears them
+	rd.Reset(bytes.NewReader(bts))
+	if _, err := rd.Peek(200); err != nil {
+		t.Fatalf("expected Reset to clear the buffer limit; got %v", err)
+	}
+	out, _ = rd.ReadAll()
+	if len(out) != 500 || tee.Len() != 200 {
+		t.Fatalf("expected Reset to clear the limit and tee; read %d bytes, teed %d", len(out), tee.Len())
+	}
+	if line, col := rd.Position(); line != 1 || col != 1 {
+		t.Fatalf("expected Reset to disable tracking; got line %d, col %d", line, col)
+	}
+	if _, err := rd.Next(1); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected Reset to restore the EOF policy; got %v", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	bts := randomBts(5000)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.Next(10)
+
+	var w bytes.Buffer
+	n, err := rd.Copy(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4990 || !bytes.Equal(w.Bytes(), bts[10:]) {
+		t.Fatalf("expected to copy %d bytes; copied %d", 4990, n)
+	}
+	if rd.Offset() != 5000 {
+		t.Fatalf("expected Offset() to be %d; got %d", 5000, rd.Offset())
+	}
+	if rd.BytesFilled() != 5000 {
+		t.Fatalf("expected BytesFilled() to be %d; got %d", 5000, rd.BytesFilled())
+	}
+
+	// with a tee, the bytes go through the buffer
+	var tee bytes.Buffer
+	rd.Reset(bytes.NewReader(bts))
+	rd.Limit(3000)
+	rd.Tee(&tee)
+	w.Reset()
+	if n, err = rd.Copy(&w); n != 3000 || err != nil {
+		t.Fatalf("expected (%d, nil); got (%d, %v)", 3000, n, err)
+	}
+	if !bytes.Equal(tee.Bytes(), bts[:3000]) {
+		t.Fatal("teed bytes not equal")
+	}
+
+	boom := errors.New("boom")
+	rd.Reset(&errReader{r: bytes.NewReader(bts), err: boom})
+	if _, err = rd.Copy(ioutil.Discard); err != boom {
+		t.Fatalf("expected %v; got %v", boom, err)
+	}
+}
+
+func benchmarkCopy(b *testing.B, fn func(rd *Reader, w io.Writer) (int64, error)) {
+	bts := randomBts(1 << 20)
+	rd := NewReader(nil)
+	b.SetBytes(int64(len(bts)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rd.Reset(bytes.NewReader(bts))
+		if _, err := fn(rd, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopy(b *testing.B) { benchmarkCopy(b, (*Reader).Copy) }
+
+func BenchmarkWriteTo(b *testing.B) { benchmarkCopy(b, (*Reader).WriteTo) }
+
+// BenchmarkWriteToFileSocket copies a file to a TCP
+// connection, either handing the file to the
+// connection's ReadFrom (which can use sendfile)
+// or writing it through the buffer
+func BenchmarkWriteToFileSocket(b *testing.B) {
+	f, err := ioutil.TempFile("", "fwd")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	bts := randomBts(8 << 20)
+	if _, err := f.Write(bts); err != nil {
+		b.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Skip(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(ioutil.Discard, c)
+				c.Close()
+			}()
+		}
+	}()
+
+	for _, bc := range []struct {
+		name string
+		wrap func(c net.Conn) io.Writer
+	}{
+		{"ReadFrom", func(c net.Conn) io.Writer { return c }},
+		{"Write", func(c net.Conn) io.Writer { return struct{ io.Writer }{c} }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			c, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer c.Close()
+			w := bc.wrap(c)
+			rd := NewReader(nil)
+			b.SetBytes(int64(len(bts)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					b.Fatal(err)
+				}
+				rd.Reset(f)
+				if _, err := rd.WriteTo(w); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteToBuffered(t *testing.T) {
+	bts := randomBts(10000)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.Peek(10)
+	rd.Next(5)
+
+	// writes larger than the transfer buffer fail
+	w := smallWriter{max: 4096}
+	n, err := rd.WriteToBuffered(&w, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(bts)-5) {
+		t.Fatalf("expected to write %d bytes; wrote %d", len(bts)-5, n)
+	}
+	if !bytes.Equal(w.buf.Bytes(), bts[5:]) {
+		t.Fatal("bytes not equal")
+	}
+	if rd.Offset() != int64(len(bts)) {
+		t.Fatalf("expected Offset() to be %d; got %d", len(bts), rd.Offset())
+	}
+	if rd.BytesFilled() != int64(len(bts)) {
+		t.Fatalf("expected BytesFilled() to be %d; got %d", len(bts), rd.BytesFilled())
+	}
+
+	// the limit still applies
+	rd.Reset(bytes.NewReader(bts))
+	rd.Limit(3000)
+	var buf bytes.Buffer
+	if n, err = rd.WriteToBuffered(struct{ io.Writer }{&buf}, 1024); n != 3000 || err != nil {
+		t.Fatalf("expected (%d, nil); got (%d, %v)", 3000, n, err)
+	}
+	if !bytes.Equal(buf.Bytes(), bts[:3000]) {
+		t.Fatal("bytes not equal")
+	}
+
+	boom := errors.New("boom")
+	rd.Reset(&errReader{r: bytes.NewReader(bts), err: boom})
+	if _, err = rd.WriteToBuffered(ioutil.Discard, 1<<16); err != boom {
+		t.Fatalf("expected %v; got %v", boom, err)
+	}
+}
+
+// benchmarkSmallBuffer streams through a 2KB
+// buffer to a writer without ReadFrom; reads/op
+// counts the reads made on the source
+func benchmarkSmallBuffer(b *testing.B, fn func(rd *Reader, w io.Writer) (int64, error)) {
+	bts := randomBts(1 << 20)
+	src := &readCounter{}
+	rd := NewReaderSize(nil, 2048)
+	w := struct{ io.Writer }{ioutil.Discard}
+	b.SetBytes(int64(len(bts)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		src.r = bytes.NewReader(bts)
+		rd.Reset(src)
+		if _, err := fn(rd, w); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(src.count)/float64(b.N), "reads/op")
+}
+
+func BenchmarkWriteToSmallBuffer(b *testing.B) { benchmarkSmallBuffer(b, (*Reader).WriteTo) }
+
+func BenchmarkWriteToBuffered(b *testing.B) {
+	benchmarkSmallBuffer(b, func(rd *Reader, w io.Writer) (int64, error) {
+		return rd.WriteToBuffered(w, 64<<10)
+	})
+}
+
+func TestPeekDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go server.Write([]byte("0123456789"))
+
+	rd := NewReaderSize(client, 32)
+	b, err := rd.PeekDeadline(20, time.Now().Add(50*time.Millisecond))
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("expected a timeout; got %v", err)
+	}
+	if string(b) != "0123456789" {
+		t.Fatalf("expected %q; got %q", "0123456789", b)
+	}
+
+	// the deadline was cleared
+	go server.Write([]byte("abcdefghij"))
+	b, err = rd.PeekDeadline(20, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "0123456789abcdefghij" {
+		t.Fatalf("expected %q; got %q", "0123456789abcdefghij", b)
+	}
+
+	rd.Reset(bytes.NewReader(nil))
+	if _, err := rd.PeekDeadline(1, time.Now()); err != ErrNoDeadline {
+		t.Fatalf("expected ErrNoDeadline; got %v", err)
+	}
+}
+
+func TestIndexPattern(t *testing.T) {
+	pat := []byte("needle")
+	for _, at := range []int{0, 10, 13, 14, 15, 16, 100} {
+		in := append(bytes.Repeat([]byte("n"), at), "needle and more"...)
+		// a 16-byte buffer and one-byte reads
+		// put the match across every seam
+		rd := NewReaderSize(iotest.OneByteReader(bytes.NewReader(in)), 16)
+		i, err := rd.IndexPattern(pat)
+		if err != nil {
+			t.Fatalf("at %d: %v", at, err)
+		}
+		if i != at {
+			t.Fatalf("expected index %d; got %d", at, i)
+		}
+		if rd.Offset() != 0 {
+			t.Fatalf("expected Offset() to be 0; got %d", rd.Offset())
+		}
+	}
+
+	rd := NewReaderSize(strings.NewReader("no match here, needl"), 16)
+	rd.Next(3)
+	if i, err := rd.IndexPattern(pat); i != -1 || err != io.EOF {
+		t.Fatalf("expected (-1, io.EOF); got (%d, %v)", i, err)
+	}
+	if i, err := rd.IndexPattern(nil); i != 0 || err != nil {
+		t.Fatalf("expected (0, nil); got (%d, %v)", i, err)
+	}
+}
+
+func TestReadUntilFunc(t *testing.T) {
+	crlf := func(prev, cur byte) bool { return prev == '\r' && cur == '\n' }
+	in := "a\nb\r\n" + strings.Repeat("c", 15) + "\r\n" + "rest\r"
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+
+	for _, want := range []string{"a\nb\r\n", strings.Repeat("c", 15) + "\r\n"} {
+		b, err := rd.ReadUntilFunc(crlf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Fatalf("expected %q; got %q", want, b)
+		}
+	}
+	b, err := rd.ReadUntilFunc(crlf)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if string(b) != "rest\r" {
+		t.Fatalf("expected %q; got %q", "rest\r", b)
+	}
+}
+
+func TestAtEOF(t *testing.T) {
+	rd := NewReaderSize(partialReader{strings.NewReader("abc")}, 16)
+	for i := 0; i < 3; i++ {
+		eof, err := rd.AtEOF()
+		if eof || err != nil {
+			t.Fatalf("expected (false, <nil>) before byte %d; got (%v, %v)", i, eof, err)
+		}
+		rd.ReadByte()
+	}
+	for i := 0; i < 2; i++ {
+		eof, err := rd.AtEOF()
+		if !eof || err != nil {
+			t.Fatalf("expected (true, <nil>); got (%v, %v)", eof, err)
+		}
+	}
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+
+	// other errors are not the end of the stream
+	boom := errors.New("boom")
+	rd = NewReaderSize(&errReader{r: strings.NewReader("x"), err: boom}, 16)
+	rd.ReadByte()
+	if eof, err := rd.AtEOF(); eof || err != boom {
+		t.Fatalf("expected (false, %q); got (%v, %v)", boom, eof, err)
+	}
+
+	rd = NewReaderSize(strings.NewReader("abcdef"), 16)
+	rd.Limit(2)
+	rd.Skip(2)
+	if eof, err := rd.AtEOF(); !eof || err != nil {
+		t.Fatalf("expected (true, <nil>) at the limit; got (%v, %v)", eof, err)
+	}
+}
+
+func TestSetPeekGrowsBuffer(t *testing.T) {
+	bts := randomBts(200)
+	rd := NewReaderSize(bytes.NewReader(bts), 16)
+	rd.SetPeekGrowsBuffer(false)
+
+	rd.Peek(4)
+	p, err := rd.Peek(40)
+	if err != ErrBufferFull {
+		t.Fatalf("expected %q; got %v", ErrBufferFull, err)
+	}
+	if !bytes.Equal(p, bts[:len(p)]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if rd.BufferSize() != 16 {
+		t.Fatalf("expected BufferSize() to be %d; got %d", 16, rd.BufferSize())
+	}
+
+	// requests that fit are unaffected
+	p, err = rd.Peek(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[:16]) {
+		t.Fatal("peeked bytes not equal")
+	}
+
+	rd.SetPeekGrowsBuffer(true)
+	p, err = rd.Peek(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[:40]) {
+		t.Fatal("peeked bytes not equal")
+	}
+}
+
+type closeCounter struct {
+	io.Reader
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	src := &closeCounter{Reader: strings.NewReader("hello")}
+	var rc io.ReadCloser = NewReaderSize(src, 16)
+	rd := rc.(*Reader)
+	rd.Peek(1)
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if src.closed != 1 {
+		t.Fatalf("expected 1 call to Close(); got %d", src.closed)
+	}
+
+	// buffered bytes are still available
+	b, err := rd.Next(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Fatalf("expected %q; got %q", "hello", b)
+	}
+
+	rd.Reset(strings.NewReader("x"))
+	if err := rd.Close(); err != ErrNotCloser {
+		t.Fatalf("expected %q; got %v", ErrNotCloser, err)
+	}
+	if src.closed != 1 {
+		t.Fatalf("expected 1 call to Close(); got %d", src.closed)
+	}
+}
+
+// BenchmarkReadByteBuffered compares ReadByte
+// with readByteSlow, which is how ReadByte handled
+// every byte before it had a fast path
+func BenchmarkReadByteBuffered(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		fn   func(*Reader) (byte, error)
+	}{
+		{"fast", (*Reader).ReadByte},
+		{"slow", (*Reader).readByteSlow},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			bts := randomBts(64 * 1024)
+			rd := NewReaderSize(bytes.NewReader(bts), 64*1024)
+			rd.Peek(len(bts))
+			b.SetBytes(1)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.fn(rd); err != nil {
+					rd.Reset(bytes.NewReader(bts))
+					rd.Peek(len(bts))
+				}
+			}
+		})
+	}
+}
+
+func TestResetWith(t *testing.T) {
+	bts := randomBts(5000)
+	prefix := []byte(strings.Repeat("line\n", 8))
+	rd := NewReaderSize(nil, 16)
+	rd.ResetWith(bytes.NewReader(bts), prefix)
+	rd.EnablePositionTracking()
+
+	if rd.BufferSize() < len(prefix) {
+		t.Fatalf("expected BufferSize() >= %d; got %d", len(prefix), rd.BufferSize())
+	}
+	if rd.Offset() != -int64(len(prefix)) {
+		t.Fatalf("expected Offset() to be %d; got %d", -len(prefix), rd.Offset())
+	}
+	p, err := rd.Next(len(prefix) + 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p[:len(prefix)], prefix) || !bytes.Equal(p[len(prefix):], bts[:10]) {
+		t.Fatal("bytes not equal")
+	}
+	if line, _ := rd.Position(); line < 9 {
+		t.Fatalf("expected the prefix to be counted; got line %d", line)
+	}
+
+	// a long skip seeks the underlying reader
+	rd = NewReaderSize(nil, 16)
+	rd.ResetWith(bytes.NewReader(bts), prefix)
+	if _, err := rd.Skip(len(prefix) + 3000); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Reads() != 0 {
+		t.Fatalf("expected no reads; got %d", rd.Reads())
+	}
+	if rd.Offset() != 3000 {
+		t.Fatalf("expected Offset() to be %d; got %d", 3000, rd.Offset())
+	}
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[3000] {
+		t.Fatalf("expected %d; got %d", bts[3000], b)
+	}
+}
+
+func TestPeekUpTo(t *testing.T) {
+	bts := randomBts(50)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+
+	// a short Peek returns everything buffered
+	p, err := rd.Peek(100)
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+	if len(p) != rd.Buffered() || !bytes.Equal(p, bts) {
+		t.Fatalf("expected all %d buffered bytes; got %d", rd.Buffered(), len(p))
+	}
+
+	rd = NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+	p, err = rd.PeekUpTo(10)
+	if err != nil || !bytes.Equal(p, bts[:10]) {
+		t.Fatalf("expected 10 bytes and no error; got %d and %v", len(p), err)
+	}
+	p, err = rd.PeekUpTo(100)
+	if err != nil || !bytes.Equal(p, bts) {
+		t.Fatalf("expected %d bytes and no error; got %d and %v", len(bts), len(p), err)
+	}
+	rd.Skip(len(bts))
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+
+	// real errors are still returned
+	boom := errors.New("boom")
+	rd = NewReaderSize(&errReader{r: bytes.NewReader(bts[:5]), err: boom}, 16)
+	p, err = rd.PeekUpTo(10)
+	if err != boom || !bytes.Equal(p, bts[:5]) {
+		t.Fatalf("expected 5 bytes and %q; got %d and %v", boom, len(p), err)
+	}
+}
+
+func TestSwapReader(t *testing.T) {
+	rd := NewReaderSize(strings.NewReader("hello|old"), 16)
+	if _, err := rd.ReadSlice('|'); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Buffered() != 3 {
+		t.Fatalf("expected 3 buffered bytes; got %d", rd.Buffered())
+	}
+
+	src := &closeCounter{Reader: strings.NewReader("new")}
+	rd.SwapReader(src)
+	if rd.Offset() != 6 {
+		t.Fatalf("expected Offset() to be %d; got %d", 6, rd.Offset())
+	}
+	all, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(all) != "oldnew" {
+		t.Fatalf("expected %q; got %q", "oldnew", all)
+	}
+	if rd.Offset() != 12 {
+		t.Fatalf("expected Offset() to be %d; got %d", 12, rd.Offset())
+	}
+	if err := rd.Close(); err != nil || src.closed != 1 {
+		t.Fatalf("expected Close() to reach the new reader; got %v and %d calls", err, src.closed)
+	}
+
+	// the old reader's error is cleared
+	boom := errors.New("boom")
+	rd = NewReaderSize(&errReader{r: iotest.DataErrReader(strings.NewReader("ab")), err: boom}, 16)
+	rd.Peek(2)
+	rd.SwapReader(strings.NewReader("cd"))
+	if all, err = rd.ReadAll(); err != nil || string(all) != "abcd" {
+		t.Fatalf("expected (%q, <nil>); got (%q, %v)", "abcd", all, err)
+	}
+}
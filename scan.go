@@ -0,0 +1,118 @@
+package fwd
+
+import (
+	"bufio"
+	"io"
+)
+
+// SetSplit sets the split function used by
+// [Reader.Scan]. The default is [bufio.ScanLines].
+// Any [bufio.SplitFunc], such as [bufio.ScanWords]
+// or [bufio.ScanRunes], may be used.
+func (r *Reader) SetSplit(split bufio.SplitFunc) {
+	r.split = split
+}
+
+// Scan advances the reader to the next token, as
+// determined by the split function set with
+// [Reader.SetSplit], which is then available through
+// [Reader.Bytes] and [Reader.Text]. It works like
+// [bufio.Scanner.Scan]: when the split function asks
+// for more data by returning (0, nil, nil), the buffer
+// is filled (and grown if it is full), and once the
+// stream ends the split function is called with
+// atEOF set to deliver any final token. Unlike a
+// [bufio.Scanner], the reader can still be used
+// normally between calls to Scan; Scan picks up
+// wherever the reader is.
+//
+// A split function may return [bufio.ErrFinalToken]
+// to end the scan early; the reader is left just past
+// the final token, so the rest of the stream can be
+// read with other methods.
+//
+// Scan returns false when scanning stops, either
+// at the end of the stream or because of an error,
+// which is then returned by [Reader.ScanErr]. A buffer
+// that would have to grow beyond the limit set with
+// [Reader.SetMaxBufferSize] stops the scan with
+// [ErrBufferLimitExceeded]. Once Scan has returned
+// false, it keeps returning false until the reader
+// is reset.
+func (r *Reader) Scan() bool {
+	r.lastByte = -1
+	r.lastRuneSize = -1
+	r.token = nil
+	if r.scanDone {
+		return false
+	}
+	split := r.split
+	if split == nil {
+		split = bufio.ScanLines
+	}
+	for {
+		// give the split function a chance with
+		// whatever is buffered; at the end of the
+		// stream it is called even if that is nothing
+		atEOF := r.state != nil
+		if r.buffered() > 0 || atEOF {
+			advance, token, err := split(r.data[r.n:], atEOF)
+			if err != nil && err != bufio.ErrFinalToken {
+				return r.stopScan(err)
+			}
+			if advance < 0 {
+				return r.stopScan(bufio.ErrNegativeAdvance)
+			}
+			if advance > r.buffered() {
+				return r.stopScan(bufio.ErrAdvanceTooFar)
+			}
+			r.discard(advance)
+			if err == bufio.ErrFinalToken {
+				// leave the reader just past
+				// the final token
+				r.token = token
+				r.scanDone = true
+				return token != nil
+			}
+			if token != nil {
+				r.token = token
+				return true
+			}
+			if advance > 0 {
+				continue
+			}
+		}
+		if atEOF {
+			err := r.err()
+			if err == io.EOF {
+				err = nil
+			}
+			return r.stopScan(err)
+		}
+		r.more()
+		if r.state == ErrBufferLimitExceeded {
+			return r.stopScan(r.err())
+		}
+	}
+}
+
+func (r *Reader) stopScan(err error) bool {
+	r.scanErr = err
+	r.scanDone = true
+	return false
+}
+
+// Bytes returns the token found by the last call
+// to [Reader.Scan]. It may alias the buffer, so it
+// is only valid until the next reader method call.
+func (r *Reader) Bytes() []byte { return r.token }
+
+// Text returns the token found by the last call
+// to [Reader.Scan] as a newly-allocated string.
+func (r *Reader) Text() string { return string(r.token) }
+
+// ScanErr returns the error that stopped [Reader.Scan],
+// or nil if it stopped at the end of the stream. It plays
+// the part of [bufio.Scanner.Err]; [Reader.Err] reports
+// the pending error from the underlying reader instead.
+func (r *Reader) ScanErr() error { return r.scanErr }
@@ -0,0 +1,88 @@
+package fwd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	in := "one two\nthree\r\n\nfour"
+	rd := NewReaderSize(partialReader{strings.NewReader(in)}, 16)
+	var lines []string
+	for rd.Scan() {
+		lines = append(lines, rd.Text())
+	}
+	if err := rd.ScanErr(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(lines, "|"); got != "one two|three||four" {
+		t.Fatalf("expected %q; got %q", "one two|three||four", got)
+	}
+	if rd.Scan() {
+		t.Fatal("expected Scan() to keep returning false")
+	}
+
+	// a token longer than the buffer grows it
+	long := strings.Repeat("x", 100)
+	rd = NewReaderSize(partialReader{strings.NewReader("a " + long + " b")}, 16)
+	rd.SetSplit(bufio.ScanWords)
+	var words []string
+	for rd.Scan() {
+		words = append(words, string(rd.Bytes()))
+	}
+	if rd.ScanErr() != nil || len(words) != 3 || words[1] != long {
+		t.Fatalf("expected 3 words; got %q and %v", words, rd.ScanErr())
+	}
+
+	rd = NewReaderSize(strings.NewReader("a "+long), 16)
+	rd.SetSplit(bufio.ScanWords)
+	rd.SetMaxBufferSize(64)
+	if !rd.Scan() || rd.Text() != "a" {
+		t.Fatalf("expected %q; got %q", "a", rd.Text())
+	}
+	if rd.Scan() {
+		t.Fatal("expected the long word to stop the scan")
+	}
+	if rd.ScanErr() != ErrBufferLimitExceeded {
+		t.Fatalf("expected %q; got %v", ErrBufferLimitExceeded, rd.ScanErr())
+	}
+}
+
+func TestScanFinalToken(t *testing.T) {
+	rd := NewReaderSize(strings.NewReader("a,b,STOP,c"), 16)
+	rd.SetSplit(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if i := bytes.IndexByte(data, ','); i >= 0 {
+			advance, token, err = i+1, data[:i], nil
+		}
+		if string(token) == "STOP" {
+			return advance, token, bufio.ErrFinalToken
+		}
+		return advance, token, err
+	})
+	var toks []string
+	for rd.Scan() {
+		toks = append(toks, rd.Text())
+	}
+	if rd.ScanErr() != nil || strings.Join(toks, "") != "abSTOP" {
+		t.Fatalf("expected a, b, and STOP; got %q and %v", toks, rd.ScanErr())
+	}
+
+	// the reader is still usable afterwards
+	rest, err := rd.ReadAll()
+	if err != nil || string(rest) != "c" {
+		t.Fatalf("expected (%q, <nil>); got (%q, %v)", "c", rest, err)
+	}
+
+	boom := errors.New("boom")
+	rd.Reset(strings.NewReader("abc"))
+	rd.SetSplit(func(data []byte, atEOF bool) (int, []byte, error) {
+		return 0, nil, boom
+	})
+	if rd.Scan() || rd.ScanErr() != boom {
+		t.Fatalf("expected %q; got %v", boom, rd.ScanErr())
+	}
+}
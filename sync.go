@@ -0,0 +1,828 @@
+package fwd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash"
+	"io"
+	"sync"
+	"time"
+)
+
+// SyncReader wraps a [Reader] with a mutex so
+// that it can be used from multiple goroutines.
+// Every [Reader] method has a SyncReader counterpart
+// that locks the mutex and calls it (the iterators
+// hold the lock for each step rather than for the
+// whole loop); use [SyncReader.Do] to hold the lock
+// across several calls (for example, a Peek followed
+// by a Skip).
+//
+// Locking does not extend the lifetime of slices
+// that alias the read buffer: as with [Reader], they
+// are only valid until the next method call, which
+// may now come from another goroutine.
+type SyncReader struct {
+	mu sync.Mutex
+	r  *Reader
+}
+
+// NewSyncReader returns a new *SyncReader
+// that serializes access to 'r'.
+func NewSyncReader(r *Reader) *SyncReader {
+	return &SyncReader{r: r}
+}
+
+// Do calls 'fn' with the underlying
+// [Reader] while holding the lock.
+// 'fn' must not retain the *Reader.
+func (s *SyncReader) Do(fn func(r *Reader)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.r)
+}
+
+// Reset calls [Reader.Reset] while holding the lock.
+func (s *SyncReader) Reset(rd io.Reader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Reset(rd)
+}
+
+// ResetSize calls [Reader.ResetSize] while holding the lock.
+func (s *SyncReader) ResetSize(rd io.Reader, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.ResetSize(rd, n)
+}
+
+// ResetBuf calls [Reader.ResetBuf] while holding the lock.
+func (s *SyncReader) ResetBuf(rd io.Reader, buf []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.ResetBuf(rd, buf)
+}
+
+// Release calls [Reader.Release] while holding the lock.
+func (s *SyncReader) Release() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Release()
+}
+
+// Limit calls [Reader.Limit] while holding the lock.
+func (s *SyncReader) Limit(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Limit(n)
+}
+
+// Err calls [Reader.Err] while holding the lock.
+func (s *SyncReader) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Err()
+}
+
+// ClearErr calls [Reader.ClearErr] while holding the lock.
+func (s *SyncReader) ClearErr() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.ClearErr()
+}
+
+// SetPromoteEOF calls [Reader.SetPromoteEOF] while holding the lock.
+func (s *SyncReader) SetPromoteEOF(promote bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetPromoteEOF(promote)
+}
+
+// Tee calls [Reader.Tee] while holding the lock.
+func (s *SyncReader) Tee(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Tee(w)
+}
+
+// EnablePositionTracking calls [Reader.EnablePositionTracking] while holding the lock.
+func (s *SyncReader) EnablePositionTracking() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.EnablePositionTracking()
+}
+
+// Position calls [Reader.Position] while holding the lock.
+func (s *SyncReader) Position() (line, col int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Position()
+}
+
+// Buffered calls [Reader.Buffered] while holding the lock.
+func (s *SyncReader) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Buffered()
+}
+
+// BufferSize calls [Reader.BufferSize] while holding the lock.
+func (s *SyncReader) BufferSize() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.BufferSize()
+}
+
+// Offset calls [Reader.Offset] while holding the lock.
+func (s *SyncReader) Offset() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Offset()
+}
+
+// Grow calls [Reader.Grow] while holding the lock.
+func (s *SyncReader) Grow(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Grow(n)
+}
+
+// PeekAll calls [Reader.PeekAll] while holding the lock.
+func (s *SyncReader) PeekAll() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekAll()
+}
+
+// Fill calls [Reader.Fill] while holding the lock.
+func (s *SyncReader) Fill() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Fill()
+}
+
+// Peek calls [Reader.Peek] while holding the lock.
+func (s *SyncReader) Peek(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Peek(n)
+}
+
+// PeekContext calls [Reader.PeekContext] while holding the lock.
+func (s *SyncReader) PeekContext(ctx context.Context, n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekContext(ctx, n)
+}
+
+// PeekUntil calls [Reader.PeekUntil] while holding the lock.
+func (s *SyncReader) PeekUntil(delim byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekUntil(delim)
+}
+
+// IndexByte calls [Reader.IndexByte] while holding the lock.
+func (s *SyncReader) IndexByte(c byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.IndexByte(c)
+}
+
+// Skip calls [Reader.Skip] while holding the lock.
+func (s *SyncReader) Skip(n int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Skip(n)
+}
+
+// Discard calls [Reader.Discard] while holding the lock.
+func (s *SyncReader) Discard(n int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Discard(n)
+}
+
+// Seek calls [Reader.Seek] while holding the lock.
+func (s *SyncReader) Seek(offset int64, whence int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Seek(offset, whence)
+}
+
+// SkipUntil calls [Reader.SkipUntil] while holding the lock.
+func (s *SyncReader) SkipUntil(delim byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.SkipUntil(delim)
+}
+
+// Next calls [Reader.Next] while holding the lock.
+func (s *SyncReader) Next(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Next(n)
+}
+
+// Read calls [Reader.Read] while holding the lock.
+func (s *SyncReader) Read(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Read(b)
+}
+
+// ReadFull calls [Reader.ReadFull] while holding the lock.
+func (s *SyncReader) ReadFull(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadFull(b)
+}
+
+// ReadFullContext calls [Reader.ReadFullContext] while holding the lock.
+func (s *SyncReader) ReadFullContext(ctx context.Context, b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadFullContext(ctx, b)
+}
+
+// ReadByte calls [Reader.ReadByte] while holding the lock.
+func (s *SyncReader) ReadByte() (byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadByte()
+}
+
+// PeekByte calls [Reader.PeekByte] while holding the lock.
+func (s *SyncReader) PeekByte() (byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekByte()
+}
+
+// UnreadByte calls [Reader.UnreadByte] while holding the lock.
+func (s *SyncReader) UnreadByte() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.UnreadByte()
+}
+
+// ReadRune calls [Reader.ReadRune] while holding the lock.
+func (s *SyncReader) ReadRune() (rune, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadRune()
+}
+
+// UnreadRune calls [Reader.UnreadRune] while holding the lock.
+func (s *SyncReader) UnreadRune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.UnreadRune()
+}
+
+// PeekRune calls [Reader.PeekRune] while holding the lock.
+func (s *SyncReader) PeekRune() (rune, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekRune()
+}
+
+// ReadBytes calls [Reader.ReadBytes] while holding the lock.
+func (s *SyncReader) ReadBytes(delim byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadBytes(delim)
+}
+
+// ReadString calls [Reader.ReadString] while holding the lock.
+func (s *SyncReader) ReadString(delim byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadString(delim)
+}
+
+// ReadLine calls [Reader.ReadLine] while holding the lock.
+func (s *SyncReader) ReadLine() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadLine()
+}
+
+// Mark calls [Reader.Mark] while holding the lock.
+func (s *SyncReader) Mark() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Mark()
+}
+
+// Rewind calls [Reader.Rewind] while holding the lock.
+func (s *SyncReader) Rewind(mark int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Rewind(mark)
+}
+
+// Unmark calls [Reader.Unmark] while holding the lock.
+func (s *SyncReader) Unmark(mark int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Unmark(mark)
+}
+
+// ReadAll calls [Reader.ReadAll] while holding the lock.
+func (s *SyncReader) ReadAll() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadAll()
+}
+
+// ScanBytes calls [Reader.ScanBytes] while holding the lock.
+func (s *SyncReader) ScanBytes(fn func([]byte) (consumed int, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ScanBytes(fn)
+}
+
+// WriteTo calls [Reader.WriteTo] while holding the lock.
+func (s *SyncReader) WriteTo(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.WriteTo(w)
+}
+
+// ReadUint16 calls [Reader.ReadUint16] while holding the lock.
+func (s *SyncReader) ReadUint16(bo binary.ByteOrder) (uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadUint16(bo)
+}
+
+// ReadUint32 calls [Reader.ReadUint32] while holding the lock.
+func (s *SyncReader) ReadUint32(bo binary.ByteOrder) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadUint32(bo)
+}
+
+// ReadUint64 calls [Reader.ReadUint64] while holding the lock.
+func (s *SyncReader) ReadUint64(bo binary.ByteOrder) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadUint64(bo)
+}
+
+// ReadUvarint calls [Reader.ReadUvarint] while holding the lock.
+func (s *SyncReader) ReadUvarint() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadUvarint()
+}
+
+// ReadVarint calls [Reader.ReadVarint] while holding the lock.
+func (s *SyncReader) ReadVarint() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadVarint()
+}
+
+// SkipWhile calls [Reader.SkipWhile] while holding the lock.
+func (s *SyncReader) SkipWhile(pred func(byte) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.SkipWhile(pred)
+}
+
+// PeekWhile calls [Reader.PeekWhile] while holding the lock.
+func (s *SyncReader) PeekWhile(pred func(byte) bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekWhile(pred)
+}
+
+// ReadAtLeast calls [Reader.ReadAtLeast] while holding the lock.
+func (s *SyncReader) ReadAtLeast(b []byte, min int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadAtLeast(b, min)
+}
+
+// SetMaxEmptyReads calls [Reader.SetMaxEmptyReads] while holding the lock.
+func (s *SyncReader) SetMaxEmptyReads(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetMaxEmptyReads(n)
+}
+
+// Available calls [Reader.Available] while holding the lock.
+func (s *SyncReader) Available() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Available()
+}
+
+// Cap calls [Reader.Cap] while holding the lock.
+func (s *SyncReader) Cap() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Cap()
+}
+
+// PushBack calls [Reader.PushBack] while holding the lock.
+func (s *SyncReader) PushBack(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PushBack(b)
+}
+
+// ReadCString calls [Reader.ReadCString] while holding the lock.
+func (s *SyncReader) ReadCString() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadCString()
+}
+
+// SetMaxTokenSize calls [Reader.SetMaxTokenSize] while holding the lock.
+func (s *SyncReader) SetMaxTokenSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetMaxTokenSize(n)
+}
+
+// ReadSlice calls [Reader.ReadSlice] while holding the lock.
+func (s *SyncReader) ReadSlice(delim byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadSlice(delim)
+}
+
+// ReadInt16 calls [Reader.ReadInt16] while holding the lock.
+func (s *SyncReader) ReadInt16(bo binary.ByteOrder) (int16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadInt16(bo)
+}
+
+// ReadInt32 calls [Reader.ReadInt32] while holding the lock.
+func (s *SyncReader) ReadInt32(bo binary.ByteOrder) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadInt32(bo)
+}
+
+// ReadInt64 calls [Reader.ReadInt64] while holding the lock.
+func (s *SyncReader) ReadInt64(bo binary.ByteOrder) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadInt64(bo)
+}
+
+// ReadInt8 calls [Reader.ReadInt8] while holding the lock.
+func (s *SyncReader) ReadInt8() (int8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadInt8()
+}
+
+// ReadFloat32 calls [Reader.ReadFloat32] while holding the lock.
+func (s *SyncReader) ReadFloat32(bo binary.ByteOrder) (float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadFloat32(bo)
+}
+
+// ReadFloat64 calls [Reader.ReadFloat64] while holding the lock.
+func (s *SyncReader) ReadFloat64(bo binary.ByteOrder) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadFloat64(bo)
+}
+
+// CopyN calls [Reader.CopyN] while holding the lock.
+func (s *SyncReader) CopyN(w io.Writer, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.CopyN(w, n)
+}
+
+// SetWriteChunkSize calls [Reader.SetWriteChunkSize] while holding the lock.
+func (s *SyncReader) SetWriteChunkSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetWriteChunkSize(n)
+}
+
+// WriteToN calls [Reader.WriteToN] while holding the lock.
+func (s *SyncReader) WriteToN(w io.Writer, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.WriteToN(w, n)
+}
+
+// ByteAt calls [Reader.ByteAt] while holding the lock.
+func (s *SyncReader) ByteAt(off int) (byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ByteAt(off)
+}
+
+// ReadAt calls [Reader.ReadAt] while holding the lock.
+func (s *SyncReader) ReadAt(b []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadAt(b, off)
+}
+
+// DropSnapshot calls [Reader.DropSnapshot] while holding the lock.
+func (s *SyncReader) DropSnapshot(st State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.DropSnapshot(st)
+}
+
+// Restore calls [Reader.Restore] while holding the lock.
+func (s *SyncReader) Restore(st State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Restore(st)
+}
+
+// Snapshot calls [Reader.Snapshot] while holding the lock.
+func (s *SyncReader) Snapshot() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Snapshot()
+}
+
+// BytesFilled calls [Reader.BytesFilled] while holding the lock.
+func (s *SyncReader) BytesFilled() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.BytesFilled()
+}
+
+// Reads calls [Reader.Reads] while holding the lock.
+func (s *SyncReader) Reads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Reads()
+}
+
+// ReadFixed calls [Reader.ReadFixed] while holding the lock.
+func (s *SyncReader) ReadFixed(n int, dst []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadFixed(n, dst)
+}
+
+// Compact calls [Reader.Compact] while holding the lock.
+func (s *SyncReader) Compact() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Compact()
+}
+
+// ReadUntilAny calls [Reader.ReadUntilAny] while holding the lock.
+func (s *SyncReader) ReadUntilAny(delims []byte) ([]byte, byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadUntilAny(delims)
+}
+
+// Consume calls [Reader.Consume] while holding the lock.
+func (s *SyncReader) Consume(prefix []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Consume(prefix)
+}
+
+// Underlying calls [Reader.Underlying] while holding the lock.
+func (s *SyncReader) Underlying() io.Reader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Underlying()
+}
+
+// Hash calls [Reader.Hash] while holding the lock.
+func (s *SyncReader) Hash(h hash.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Hash(h)
+}
+
+// Len calls [Reader.Len] while holding the lock.
+func (s *SyncReader) Len() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Len()
+}
+
+// ReadBlock calls [Reader.ReadBlock] while holding the lock.
+func (s *SyncReader) ReadBlock(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadBlock(n)
+}
+
+// Drain calls [Reader.Drain] while holding the lock.
+func (s *SyncReader) Drain() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Drain()
+}
+
+// Size calls [Reader.Size] while holding the lock.
+func (s *SyncReader) Size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Size()
+}
+
+// SetMaxBufferSize calls [Reader.SetMaxBufferSize] while holding the lock.
+func (s *SyncReader) SetMaxBufferSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetMaxBufferSize(n)
+}
+
+// SetSeekThreshold calls [Reader.SetSeekThreshold] while holding the lock.
+func (s *SyncReader) SetSeekThreshold(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetSeekThreshold(n)
+}
+
+// ReadStringN calls [Reader.ReadStringN] while holding the lock.
+func (s *SyncReader) ReadStringN(n int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadStringN(n)
+}
+
+// SetEmptyReadError calls [Reader.SetEmptyReadError] while holding the lock.
+func (s *SyncReader) SetEmptyReadError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetEmptyReadError(err)
+}
+
+// PeekAt calls [Reader.PeekAt] while holding the lock.
+func (s *SyncReader) PeekAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekAt(p, off)
+}
+
+// PeekN calls [Reader.PeekN] while holding the lock.
+func (s *SyncReader) PeekN(sizes ...int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekN(sizes...)
+}
+
+// ShrinkTo calls [Reader.ShrinkTo] while holding the lock.
+func (s *SyncReader) ShrinkTo(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.ShrinkTo(n)
+}
+
+// PeekAvailable calls [Reader.PeekAvailable] while holding the lock.
+func (s *SyncReader) PeekAvailable(n int) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekAvailable(n)
+}
+
+// ResetKeepOptions calls [Reader.ResetKeepOptions] while holding the lock.
+func (s *SyncReader) ResetKeepOptions(rd io.Reader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.ResetKeepOptions(rd)
+}
+
+// Copy calls [Reader.Copy] while holding the lock.
+func (s *SyncReader) Copy(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Copy(w)
+}
+
+// PeekDeadline calls [Reader.PeekDeadline] while holding the lock.
+func (s *SyncReader) PeekDeadline(n int, t time.Time) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekDeadline(n, t)
+}
+
+// IndexPattern calls [Reader.IndexPattern] while holding the lock.
+func (s *SyncReader) IndexPattern(pat []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.IndexPattern(pat)
+}
+
+// ReadUntilFunc calls [Reader.ReadUntilFunc] while holding the lock.
+func (s *SyncReader) ReadUntilFunc(fn func(prev, cur byte) bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadUntilFunc(fn)
+}
+
+// SetReadAhead calls [Reader.SetReadAhead] while holding the lock.
+func (s *SyncReader) SetReadAhead(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetReadAhead(n)
+}
+
+// AtEOF calls [Reader.AtEOF] while holding the lock.
+func (s *SyncReader) AtEOF() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.AtEOF()
+}
+
+// SetPeekGrowsBuffer calls [Reader.SetPeekGrowsBuffer] while holding the lock.
+func (s *SyncReader) SetPeekGrowsBuffer(grow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetPeekGrowsBuffer(grow)
+}
+
+// WriteToBuffered calls [Reader.WriteToBuffered] while holding the lock.
+func (s *SyncReader) WriteToBuffered(w io.Writer, bufSize int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.WriteToBuffered(w, bufSize)
+}
+
+// ReadVarString calls [Reader.ReadVarString] while holding the lock.
+func (s *SyncReader) ReadVarString() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ReadVarString()
+}
+
+// Close calls [Reader.Close] while holding the lock.
+func (s *SyncReader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Close()
+}
+
+// ResetWith calls [Reader.ResetWith] while holding the lock.
+func (s *SyncReader) ResetWith(rd io.Reader, prefix []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.ResetWith(rd, prefix)
+}
+
+// PeekUpTo calls [Reader.PeekUpTo] while holding the lock.
+func (s *SyncReader) PeekUpTo(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.PeekUpTo(n)
+}
+
+// SwapReader calls [Reader.SwapReader] while holding the lock.
+func (s *SyncReader) SwapReader(rd io.Reader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SwapReader(rd)
+}
+
+// Bytes calls [Reader.Bytes] while holding the lock.
+func (s *SyncReader) Bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Bytes()
+}
+
+// Scan calls [Reader.Scan] while holding the lock.
+func (s *SyncReader) Scan() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Scan()
+}
+
+// ScanErr calls [Reader.ScanErr] while holding the lock.
+func (s *SyncReader) ScanErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.ScanErr()
+}
+
+// SetSplit calls [Reader.SetSplit] while holding the lock.
+func (s *SyncReader) SetSplit(split bufio.SplitFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.SetSplit(split)
+}
+
+// Text calls [Reader.Text] while holding the lock.
+func (s *SyncReader) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Text()
+}
@@ -0,0 +1,44 @@
+//go:build go1.23
+// +build go1.23
+
+package fwd
+
+import "iter"
+
+// Blocks is like [Reader.Blocks], but it holds
+// the lock while each block is read. The lock is
+// released while the loop body runs, so the body
+// may call other methods on 's'.
+func (s *SyncReader) Blocks(size int) iter.Seq2[[]byte, error] {
+	return s.locked(s.r.Blocks(size))
+}
+
+// Lines is like [Reader.Lines], but it holds
+// the lock while each line is read, and releases
+// it while the loop body runs.
+func (s *SyncReader) Lines() iter.Seq2[[]byte, error] {
+	return s.locked(s.r.Lines())
+}
+
+// locked returns an iterator that runs each
+// step of 'seq' while holding the lock, but
+// releases it around each call to yield
+func (s *SyncReader) locked(seq iter.Seq2[[]byte, error]) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		s.mu.Lock()
+		held := true
+		defer func() {
+			if held {
+				s.mu.Unlock()
+			}
+		}()
+		seq(func(b []byte, err error) bool {
+			s.mu.Unlock()
+			held = false
+			ok := yield(b, err)
+			s.mu.Lock()
+			held = true
+			return ok
+		})
+	}
+}
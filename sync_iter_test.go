@@ -0,0 +1,44 @@
+//go:build go1.23
+// +build go1.23
+
+package fwd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSyncReaderBlocks(t *testing.T) {
+	bts := randomBts(1000)
+	sr := NewSyncReader(NewReaderSize(bytes.NewReader(bts), 64))
+	var out []byte
+	for b, err := range sr.Blocks(96) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, b...)
+		// the lock is not held here
+		if sr.Offset() != int64(len(out)) {
+			t.Fatalf("expected Offset() to be %d; got %d", len(out), sr.Offset())
+		}
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestSyncReaderLines(t *testing.T) {
+	sr := NewSyncReader(NewReaderSize(strings.NewReader("a\nbb\r\nccc"), 16))
+	var got []string
+	for line, err := range sr.Lines() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(line))
+		sr.Buffered()
+	}
+	if strings.Join(got, "|") != "a|bb|ccc" {
+		t.Fatalf("expected %q; got %q", "a|bb|ccc", strings.Join(got, "|"))
+	}
+}
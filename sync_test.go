@@ -0,0 +1,83 @@
+package fwd
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSyncReader(t *testing.T) {
+	const workers = 8
+	bts := randomBts(workers * 1000)
+	sr := NewSyncReader(NewReaderSize(bytes.NewReader(bts), 64))
+
+	// each worker reads 100-byte chunks
+	// with a Peek followed by a Skip; the
+	// chunks must never interleave
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				sr.Do(func(r *Reader) {
+					off := r.Offset()
+					peek, err := r.Peek(100)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if !bytes.Equal(peek, bts[off:off+100]) {
+						t.Errorf("at %d: bytes not equal", off)
+					}
+					r.Skip(100)
+					mu.Lock()
+					seen[off] = true
+					mu.Unlock()
+				})
+				sr.Buffered()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != workers*10 {
+		t.Fatalf("expected %d distinct chunks; got %d", workers*10, len(seen))
+	}
+	if sr.Offset() != int64(len(bts)) {
+		t.Fatalf("expected Offset() to be %d; got %d", len(bts), sr.Offset())
+	}
+}
+
+func TestSyncReaderMethods(t *testing.T) {
+	// every Reader method has a
+	// locked SyncReader counterpart
+	rt := reflect.TypeOf((*Reader)(nil))
+	st := reflect.TypeOf((*SyncReader)(nil))
+	for i := 0; i < rt.NumMethod(); i++ {
+		m := rt.Method(i)
+		sm, ok := st.MethodByName(m.Name)
+		if !ok {
+			t.Errorf("SyncReader is missing %s", m.Name)
+			continue
+		}
+		// compare the signatures without the receivers
+		if m.Type.NumIn() != sm.Type.NumIn() || m.Type.NumOut() != sm.Type.NumOut() {
+			t.Errorf("SyncReader.%s has a different signature", m.Name)
+			continue
+		}
+		for j := 1; j < m.Type.NumIn(); j++ {
+			if m.Type.In(j) != sm.Type.In(j) {
+				t.Errorf("SyncReader.%s has a different signature", m.Name)
+			}
+		}
+		for j := 0; j < m.Type.NumOut(); j++ {
+			if m.Type.Out(j) != sm.Type.Out(j) {
+				t.Errorf("SyncReader.%s has a different signature", m.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+)
+
+// NewReaderBytes returns a new *Reader that
+// reads from 'b'. The underlying reader is a
+// [*bytes.Reader], so the returned reader can
+// skip and seek.
+func NewReaderBytes(b []byte) *Reader {
+	return NewReader(bytes.NewReader(b))
+}
+
+// NewChunkReader returns a new *Reader that reads
+// from 'b' through an underlying reader that returns
+// at most 'chunk' bytes from each call to Read, which
+// makes it useful for testing code that has to cope
+// with short reads. The underlying reader is not an
+// [io.Seeker]. If 'chunk' is less than 1, it is 1.
+func NewChunkReader(b []byte, chunk int) *Reader {
+	return NewReader(&chunkReader{r: bytes.NewReader(b), chunk: max(chunk, 1)})
+}
+
+// chunkReader returns at most
+// 'chunk' bytes from each read
+type chunkReader struct {
+	r     *bytes.Reader
+	chunk int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.chunk {
+		p = p[:c.chunk]
+	}
+	return c.r.Read(p)
+}
+
+// Recording holds the bytes read from the
+// source of a reader returned by [NewRecordingReader].
+type Recording struct {
+	r   io.Reader
+	buf []byte
+}
+
+// NewRecordingReader returns a new *Reader that reads
+// from 'r' and a *Recording of every byte returned by
+// r.Read. Unlike [Reader.Tee], which sees bytes as they
+// are consumed, the recording includes bytes that were
+// read ahead into the buffer but never consumed, so it
+// captures exactly what was pulled from the source. The
+// returned reader does not seek or use [io.ReaderAt] on
+// 'r', since bytes passed over that way would be missing
+// from the recording. Bytes read after the reader is
+// reset to a different source are not recorded.
+func NewRecordingReader(r io.Reader) (*Reader, *Recording) {
+	rec := &Recording{r: r}
+	return NewReader(rec), rec
+}
+
+// Read implements [io.Reader] by reading from the
+// recorded source and appending the bytes it returns
+// to the recording, including any returned along
+// with an error. It is called by the reader returned
+// from [NewRecordingReader]; calling it directly
+// consumes bytes from the source behind that reader.
+func (c *Recording) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.buf = append(c.buf, p[:n]...)
+	return n, err
+}
+
+// Bytes returns the bytes recorded so far.
+// The slice is only valid until the next read
+// from the source.
+func (c *Recording) Bytes() []byte { return c.buf }
+
+// NewReplay returns a new *Reader that reads a
+// copy of the bytes recorded so far.
+func (c *Recording) NewReplay() *Reader {
+	return NewReaderBytes(append([]byte(nil), c.buf...))
+}
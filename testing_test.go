@@ -0,0 +1,89 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNewReaderBytes(t *testing.T) {
+	bts := randomBts(5000)
+	rd := NewReaderBytes(bts)
+	if _, err := rd.Seek(4000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts[4000:]) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestNewChunkReader(t *testing.T) {
+	bts := randomBts(5000)
+	rd := NewChunkReader(bts, 7)
+	if _, err := rd.Seek(0, io.SeekStart); err != ErrNotSeeker {
+		t.Fatalf("expected ErrNotSeeker; got %v", err)
+	}
+	p, err := rd.Peek(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, bts[:100]) {
+		t.Fatal("peeked bytes not equal")
+	}
+	if rd.Reads() != 15 {
+		t.Fatalf("expected %d reads of 7 bytes; got %d", 15, rd.Reads())
+	}
+	all, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, bts) {
+		t.Fatal("bytes not equal")
+	}
+}
+
+func TestNewRecordingReader(t *testing.T) {
+	bts := randomBts(5000)
+	rd, rec := NewRecordingReader(bytes.NewReader(bts))
+
+	// a skip has to read through
+	// the bytes rather than seek
+	if _, err := rd.Skip(3000); err != nil {
+		t.Fatal(err)
+	}
+	p, err := rd.Next(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the recording includes the read-ahead
+	if len(rec.Bytes()) <= 3100 {
+		t.Fatalf("expected more than %d recorded bytes; got %d", 3100, len(rec.Bytes()))
+	}
+	if !bytes.Equal(rec.Bytes(), bts[:len(rec.Bytes())]) {
+		t.Fatal("recorded bytes not equal")
+	}
+
+	replay := rec.NewReplay()
+	if _, err := replay.Skip(3000); err != nil {
+		t.Fatal(err)
+	}
+	q, err := replay.Next(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, q) {
+		t.Fatal("replayed bytes not equal")
+	}
+
+	if _, err := rd.ReadAll(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rec.Bytes(), bts) {
+		t.Fatal("recorded bytes not equal")
+	}
+}
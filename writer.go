@@ -54,6 +54,15 @@ func NewWriterBuf(w io.Writer, buf []byte) *Writer {
 	}
 }
 
+// Reset discards any buffered data and
+// sets the underlying writer to 'wr',
+// retaining the existing buffer. This
+// makes it possible to pool Writers.
+func (w *Writer) Reset(wr io.Writer) {
+	w.w = wr
+	w.buf = w.buf[:0]
+}
+
 // Buffered returns the number of buffered bytes
 // in the reader.
 func (w *Writer) Buffered() int { return len(w.buf) }
@@ -158,14 +167,19 @@ func (w *Writer) WriteByte(b byte) error {
 
 // Next returns the next 'n' free bytes
 // in the write buffer, flushing the writer
-// as necessary. Next will return `io.ErrShortBuffer`
-// if 'n' is greater than the size of the write buffer.
+// as necessary. If 'n' is greater than the
+// size of the write buffer, the buffer is
+// flushed and then grown to hold 'n' bytes.
 // Calls to 'next' increment the write position by
 // the size of the returned buffer.
 func (w *Writer) Next(n int) ([]byte, error) {
 	c, l := cap(w.buf), len(w.buf)
 	if n > c {
-		return nil, io.ErrShortBuffer
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+		w.buf = make([]byte, 0, n)
+		c, l = n, 0
 	}
 	avail := c - l
 	if avail < n {
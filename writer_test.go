@@ -261,3 +261,58 @@ func TestWriterBufCreation(t *testing.T) {
 		}
 	}
 }
+
+func TestWriterNextGrow(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 64)
+	wr.WriteString("hello")
+
+	// larger than the buffer; should
+	// flush and then grow
+	out, err := wr.Next(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 200 {
+		t.Fatalf("expected %d bytes from Next(); got %d", 200, len(out))
+	}
+	if buf.String() != "hello" {
+		t.Fatalf("expected buffered bytes to be flushed; got %q", buf.String())
+	}
+	if wr.BufferSize() < 200 {
+		t.Fatalf("expected BufferSize() >= %d; got %d", 200, wr.BufferSize())
+	}
+	for i := range out {
+		out[i] = 'x'
+	}
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 205 {
+		t.Fatalf("expected buf.Len() to be %d; got %d", 205, buf.Len())
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	var a, b bytes.Buffer
+	wr := NewWriterSize(&a, 128)
+	wr.WriteString("discarded")
+	wr.Reset(&b)
+
+	if wr.Buffered() != 0 {
+		t.Fatalf("expected 0 buffered bytes after Reset(); found %d", wr.Buffered())
+	}
+	if wr.BufferSize() != 128 {
+		t.Fatalf("expected Reset() to retain the buffer; BufferSize() is %d", wr.BufferSize())
+	}
+	wr.WriteString("kept")
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 0 {
+		t.Fatalf("expected nothing written to the old writer; got %q", a.String())
+	}
+	if b.String() != "kept" {
+		t.Fatalf("expected %q; got %q", "kept", b.String())
+	}
+}